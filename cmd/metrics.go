@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	gosync "sync"
+
+	"github.com/olegsu/rss-sync/pkg/sync"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// health keeps the errors of the last run of each binding, served on /healthz
+	health struct {
+		mux      gosync.Mutex
+		failures map[string]string
+	}
+)
+
+var (
+	lastRuns = &health{
+		failures: map[string]string{},
+	}
+)
+
+func init() {
+	prometheus.MustRegister(sync.Collectors()...)
+}
+
+// serveMetrics starts HTTP server that serves /metrics and /healthz on addr
+// nothing is served when addr is empty
+func serveMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", lastRuns.serveHTTP)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("[ERROR] Failed to serve metrics: %v\n", err)
+		}
+	}()
+}
+
+// observe records the outcome of the run of the report, the binding is healthy again once its run succeeded
+// the error of the run as a whole is kept for each of its bindings, so the run of other binding of the file does not clear it
+func (h *health) observe(r sync.Report) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for _, b := range r.Bindings {
+		name := fmt.Sprintf("%s/%s", r.Name, b.Name)
+		if r.Err == nil && b.Succeeded() {
+			delete(h.failures, name)
+			continue
+		}
+		errs := b.Errors
+		if r.Err != nil {
+			errs = append([]string{r.Err.Error()}, errs...)
+		}
+		h.failures[name] = strings.Join(errs, "; ")
+	}
+}
+
+// serveHTTP responds with 503 and the errors of the bindings whose last run failed
+func (h *health) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if len(h.failures) == 0 {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+	names := []string{}
+	for name := range h.failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s: %s\n", name, h.failures[name])
+	}
+}
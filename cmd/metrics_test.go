@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olegsu/rss-sync/pkg/sync"
+)
+
+func TestHealthObserve(t *testing.T) {
+	report := func(binding string, err error, errs ...string) sync.Report {
+		return sync.Report{
+			Name: "feed.yaml",
+			Bindings: []sync.BindingReport{
+				{Name: binding, Errors: errs},
+			},
+			Err: err,
+		}
+	}
+	tests := []struct {
+		name    string
+		reports []sync.Report
+		want    map[string]string
+	}{
+		{
+			name:    "binding that failed",
+			reports: []sync.Report{report("a", nil, "Failed to fetch")},
+			want:    map[string]string{"feed.yaml/a": "Failed to fetch"},
+		},
+		{
+			name:    "binding that succeeded after it failed",
+			reports: []sync.Report{report("a", nil, "Failed to fetch"), report("a", nil)},
+			want:    map[string]string{},
+		},
+		{
+			name:    "run that failed is not cleared by other binding of the file",
+			reports: []sync.Report{report("a", errors.New("Failed to save state")), report("b", nil)},
+			want:    map[string]string{"feed.yaml/a": "Failed to save state"},
+		},
+		{
+			name:    "run that failed with errors of the binding",
+			reports: []sync.Report{report("a", errors.New("context canceled"), "Failed to deliver item 1")},
+			want:    map[string]string{"feed.yaml/a": "context canceled; Failed to deliver item 1"},
+		},
+		{
+			name:    "run that succeeded after it failed",
+			reports: []sync.Report{report("a", errors.New("Failed to save state")), report("a", nil)},
+			want:    map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &health{
+				failures: map[string]string{},
+			}
+			for _, r := range tt.reports {
+				h.observe(r)
+			}
+			if len(h.failures) != len(tt.want) {
+				t.Fatalf("observe() failures = %v, want %v", h.failures, tt.want)
+			}
+			for name, err := range tt.want {
+				if h.failures[name] != err {
+					t.Errorf("observe() failure of %s = %q, want %q", name, h.failures[name], err)
+				}
+			}
+			w := httptest.NewRecorder()
+			h.serveHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			want := http.StatusOK
+			if len(tt.want) > 0 {
+				want = http.StatusServiceUnavailable
+			}
+			if w.Code != want {
+				t.Errorf("serveHTTP() = %d, want %d", w.Code, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+// Copyright © 2020 oleg2807@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/olegsu/rss-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planCmdOptions struct {
+		files  []string
+		state  string
+		output string
+		engine string
+	}
+)
+
+var planCmd = &cobra.Command{
+	Use:  "plan",
+	Long: "Fetch and filter the sources and print the cards that would be created without creating them",
+	Run: func(cmd *cobra.Command, args []string) {
+		if planCmdOptions.output != "table" && planCmdOptions.output != "json" {
+			dieOnError("", fmt.Errorf("Unknown output \"%s\", supported: table, json", planCmdOptions.output))
+		}
+		syncs := readSyncFiles(planCmdOptions.files)
+		dieOnError("", checkEngine(planCmdOptions.engine))
+		s, err := store.New(planCmdOptions.state)
+		dieOnError("Failed to load state", err)
+		lgr, err := buildFileLogger()
+		dieOnError("Failed to create log file", err)
+		runner := sync.NewRunner(sync.Options{
+			Logger:        log.New(os.Stdout, "", 0),
+			EngineLogger:  lgr,
+			Store:         s,
+			Engine:        planCmdOptions.engine,
+			LogsDirectory: logsDirectory(),
+		})
+		cards := []sync.PlannedItem{}
+		reports := []sync.Report{}
+		for _, cnf := range syncs {
+			fmt.Fprintf(os.Stderr, "Starting to plan sync from file %s\n", cnf.Name)
+			planned, r, err := runner.Plan(context.Background(), cnf)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[ERROR] Failed to plan sync from file %s: %v\n", cnf.Name, err)
+			}
+			cards = append(cards, planned...)
+			reports = append(reports, r)
+		}
+		if planCmdOptions.output == "json" {
+			dieOnError("", printPlanJSON(os.Stdout, cards))
+		} else {
+			dieOnError("", printPlanTable(os.Stdout, cards))
+		}
+		if code := printReports(os.Stderr, reports); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.PersistentFlags().StringArrayVarP(&planCmdOptions.files, "file", "f", nil, "Config file(s) that will be planned")
+	planCmd.PersistentFlags().StringVar(&planCmdOptions.state, "state", defaultStateFile(), "Path to the file where delivered items are stored, items found there are not planned")
+	planCmd.PersistentFlags().StringVarP(&planCmdOptions.output, "output", "o", "table", "Output format: table or json")
+	planCmd.PersistentFlags().StringVar(&planCmdOptions.engine, "engine", sync.EngineOpenIntegration, "Engine that calls the services: open-integration or native")
+}
+
+// sortPlanned returns the cards ordered by binding
+// the order the items are reported by the engine is not stable
+func sortPlanned(cards []sync.PlannedItem) []sync.PlannedItem {
+	cards = append([]sync.PlannedItem{}, cards...)
+	sort.SliceStable(cards, func(i, j int) bool {
+		if cards[i].Binding != cards[j].Binding {
+			return cards[i].Binding < cards[j].Binding
+		}
+		return cards[i].Key < cards[j].Key
+	})
+	return cards
+}
+
+func printPlanJSON(w io.Writer, cards []sync.PlannedItem) error {
+	b, err := json.MarshalIndent(sortPlanned(cards), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func printPlanTable(w io.Writer, cards []sync.PlannedItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BINDING\tTARGET\tACTION\tTITLE\tDESCRIPTION\tLABELS")
+	for _, c := range sortPlanned(cards) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Binding, c.Target, c.Action, oneLine(c.Title, 0), oneLine(c.Description, 60), strings.Join(c.Labels, ","))
+	}
+	return tw.Flush()
+}
+
+// oneLine joins the lines of s, when max is positive s is truncated to max runes
+func oneLine(s string, max int) string {
+	res := strings.Join(strings.Fields(s), " ")
+	r := []rune(res)
+	if max > 0 && len(r) > max {
+		return string(r[:max-3]) + "..."
+	}
+	return res
+}
@@ -0,0 +1,126 @@
+package cmd
+
+// Copyright © 2020 oleg2807@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewCmdOptions struct {
+		files   []string
+		sources []string
+		output  string
+	}
+)
+
+type (
+	// previewItem is one item fetched from the source as printed by the preview command
+	previewItem struct {
+		Source    string                 `json:"source"`
+		Key       string                 `json:"key"`
+		Title     string                 `json:"title"`
+		Link      string                 `json:"link"`
+		Published *time.Time             `json:"published,omitempty"`
+		Data      map[string]interface{} `json:"data"`
+	}
+)
+
+var previewCmd = &cobra.Command{
+	Use:  "preview",
+	Long: "Fetch the sources and print the items found in them before the filters run, e.g. to check the selectors of html source",
+	Run: func(cmd *cobra.Command, args []string) {
+		if previewCmdOptions.output != "table" && previewCmdOptions.output != "json" {
+			dieOnError("", fmt.Errorf("Unknown output \"%s\", supported: table, json", previewCmdOptions.output))
+		}
+		syncs := readSyncFiles(previewCmdOptions.files)
+		runner := sync.NewRunner(sync.Options{})
+		items := []previewItem{}
+		for _, cnf := range syncs {
+			for _, src := range cnf.Sources {
+				if !previewSource(src.Name) {
+					continue
+				}
+				fetched, err := runner.Fetch(context.Background(), cnf, src.Name)
+				dieOnError(fmt.Sprintf("Failed to fetch source %s from file %s", src.Name, cnf.Name), err)
+				for _, item := range fetched {
+					items = append(items, previewItem{
+						Source:    src.Name,
+						Key:       item.Key,
+						Title:     item.Meta.Title,
+						Link:      item.Meta.Link,
+						Published: item.Meta.Published,
+						Data:      item.Data,
+					})
+				}
+			}
+		}
+		if previewCmdOptions.output == "json" {
+			dieOnError("", printPreviewJSON(os.Stdout, items))
+		} else {
+			dieOnError("", printPreviewTable(os.Stdout, items))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+	previewCmd.PersistentFlags().StringArrayVarP(&previewCmdOptions.files, "file", "f", nil, "Config file(s) the sources are read from")
+	previewCmd.PersistentFlags().StringArrayVar(&previewCmdOptions.sources, "source", nil, "Name of source to preview, all the sources by default")
+	previewCmd.PersistentFlags().StringVarP(&previewCmdOptions.output, "output", "o", "table", "Output format: table or json")
+}
+
+func previewSource(name string) bool {
+	if len(previewCmdOptions.sources) == 0 {
+		return true
+	}
+	for _, s := range previewCmdOptions.sources {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func printPreviewJSON(w io.Writer, items []previewItem) error {
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func printPreviewTable(w io.Writer, items []previewItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tPUBLISHED\tTITLE\tLINK")
+	for _, i := range items {
+		published := ""
+		if i.Published != nil {
+			published = i.Published.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", i.Source, published, oneLine(i.Title, 60), i.Link)
+	}
+	return tw.Flush()
+}
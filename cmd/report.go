@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/olegsu/rss-sync/pkg/sync"
+)
+
+const (
+	exitCodeFailed        = 1
+	exitCodePartialFailed = 2
+)
+
+// printReports prints summary of all the reports and returns the exit code
+// that reflects the results
+func printReports(w io.Writer, reports []sync.Report) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tBINDING\tSTATUS\tFETCHED\tMATCHED\tSKIPPED\tDELIVERED\tFAILED\tREMOVED")
+	succeeded, failed := 0, 0
+	for _, r := range reports {
+		if r.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\tfailed: %v\t\t\t\t\t\t\n", r.Name, r.Err)
+			failed++
+		}
+		for _, b := range r.Bindings {
+			status := "success"
+			if r.Err != nil || !b.Succeeded() {
+				status = "failed"
+				failed++
+			} else {
+				succeeded++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n", r.Name, b.Name, status, b.Fetched, b.Matched, b.Skipped, b.Delivered, b.Failed, b.Removed)
+		}
+	}
+	tw.Flush()
+	if failed == 0 {
+		return 0
+	}
+	if succeeded == 0 {
+		return exitCodeFailed
+	}
+	return exitCodePartialFailed
+}
@@ -0,0 +1,139 @@
+package cmd
+
+// Copyright © 2020 oleg2807@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/olegsu/rss-sync/pkg/sync"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveCmdOptions struct {
+		files       []string
+		state       string
+		interval    time.Duration
+		metricsAddr string
+		engine      string
+	}
+)
+
+type (
+	// job runs one binding on the schedule of its sources
+	job struct {
+		file     string
+		binding  string
+		cnf      sync.Sync
+		schedule cron.Schedule
+		next     time.Time
+	}
+)
+
+var serveCmd = &cobra.Command{
+	Use:  "serve",
+	Long: "Keep running and sync each binding on the schedule of its source",
+	Run: func(cmd *cobra.Command, args []string) {
+		syncs := readSyncFiles(serveCmdOptions.files)
+		dieOnError("", checkEngine(serveCmdOptions.engine))
+		serveMetrics(serveCmdOptions.metricsAddr)
+		s, err := store.New(serveCmdOptions.state)
+		dieOnError("Failed to load state", err)
+		runner := sync.NewRunner(sync.Options{
+			Logger:        log.New(os.Stdout, "", 0),
+			Store:         s,
+			Engine:        serveCmdOptions.engine,
+			LogsDirectory: logsDirectory(),
+		})
+		jobs := []*job{}
+		for _, cnf := range syncs {
+			for _, binding := range cnf.Bindings {
+				schedule, err := sync.BindingSchedule(cnf, binding, serveCmdOptions.interval)
+				dieOnError(fmt.Sprintf("Failed to build schedule for binding \"%s\"", binding.Name), err)
+				jobs = append(jobs, &job{
+					file:     cnf.Name,
+					binding:  binding.Name,
+					cnf:      sync.ForBinding(cnf, binding),
+					schedule: schedule,
+					next:     time.Now(),
+				})
+			}
+		}
+		if len(jobs) == 0 {
+			dieOnError("", fmt.Errorf("No bindings to run"))
+		}
+
+		// the signal cancels the running sync as well
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sg := <-sig
+			fmt.Printf("Received %s, shutting down\n", sg)
+			cancel()
+		}()
+		// the bindings run one at a time, a binding that is due while other one runs starts once it finished
+		for {
+			j := nextJob(jobs)
+			timer := time.NewTimer(time.Until(j.next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				fmt.Printf("Starting to run binding %s from file %s\n", j.binding, j.file)
+				r, err := runner.Run(ctx, j.cnf)
+				if err != nil {
+					fmt.Printf("[ERROR] Failed to run binding %s: %v\n", j.binding, err)
+				}
+				lastRuns.observe(r)
+				printReports(os.Stdout, []sync.Report{r})
+				if ctx.Err() != nil {
+					return
+				}
+				j.next = j.schedule.Next(time.Now())
+				fmt.Printf("Next run of binding %s at %s\n", j.binding, j.next.Format(time.RFC3339))
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.PersistentFlags().StringArrayVarP(&serveCmdOptions.files, "file", "f", nil, "Config file(s) that will be executed")
+	serveCmd.PersistentFlags().StringVar(&serveCmdOptions.state, "state", defaultStateFile(), "Path to the file where delivered items are stored, set to empty string to disable")
+	serveCmd.PersistentFlags().DurationVar(&serveCmdOptions.interval, "interval", time.Hour, "Default interval for sources without interval or cron")
+	serveCmd.PersistentFlags().StringVar(&serveCmdOptions.metricsAddr, "metrics-addr", "", "Address to serve /metrics and /healthz on, e.g. :9090")
+	serveCmd.PersistentFlags().StringVar(&serveCmdOptions.engine, "engine", sync.EngineOpenIntegration, "Engine that calls the services: open-integration or native")
+}
+
+func nextJob(jobs []*job) *job {
+	next := jobs[0]
+	for _, j := range jobs[1:] {
+		if j.next.Before(next.next) {
+			next = j
+		}
+	}
+	return next
+}
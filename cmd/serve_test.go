@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextJob(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		next []time.Duration
+		want int
+	}{
+		{
+			name: "one job",
+			next: []time.Duration{time.Hour},
+			want: 0,
+		},
+		{
+			name: "earliest job",
+			next: []time.Duration{time.Hour, time.Minute, 2 * time.Hour},
+			want: 1,
+		},
+		{
+			name: "overdue job",
+			next: []time.Duration{time.Minute, -time.Hour, -time.Minute},
+			want: 1,
+		},
+		{
+			name: "first of the jobs due at the same time",
+			next: []time.Duration{time.Hour, time.Minute, time.Minute},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := []*job{}
+			for _, d := range tt.next {
+				jobs = append(jobs, &job{next: now.Add(d)})
+			}
+			if got := nextJob(jobs); got != jobs[tt.want] {
+				t.Errorf("nextJob() = %v, want %v", got.next, jobs[tt.want].next)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+// Copyright © 2020 oleg2807@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"fmt"
+
+	"github.com/olegsu/rss-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateCmdOptions struct {
+		files []string
+	}
+)
+
+var validateCmd = &cobra.Command{
+	Use:  "validate",
+	Long: "Validate config files without running them",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(validateCmdOptions.files) == 0 {
+			dieOnError("", fmt.Errorf("File not provided"))
+		}
+		errs := []error{}
+		for _, f := range validateCmdOptions.files {
+			errs = append(errs, sync.ValidateFile(f)...)
+		}
+		for _, err := range errs {
+			fmt.Println(err.Error())
+		}
+		if len(errs) > 0 {
+			dieOnError("", fmt.Errorf("Found %d errors", len(errs)))
+		}
+		fmt.Println("Valid")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.PersistentFlags().StringArrayVarP(&validateCmdOptions.files, "file", "f", nil, "Config file(s) that will be validated")
+}
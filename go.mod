@@ -3,8 +3,10 @@ module github.com/olegsu/rss-sync
 go 1.14
 
 require (
-	github.com/PuerkitoBio/goquery v1.5.1 // indirect
+	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/Shopify/ejson v1.2.1 // indirect
+	github.com/andybalholm/cascadia v1.1.0
+	github.com/antonmedv/expr v1.14.3
 	github.com/aws/aws-sdk-go v1.30.19 // indirect
 	github.com/boltdb/bolt v1.3.1 // indirect
 	github.com/docker/libkv v0.2.1 // indirect
@@ -16,17 +18,24 @@ require (
 	github.com/hairyhenderson/toml v0.3.0 // indirect
 	github.com/hashicorp/consul/api v1.4.0 // indirect
 	github.com/hashicorp/vault/api v1.0.4 // indirect
+	github.com/itchyny/gojq v0.12.4
 	github.com/mmcdole/gofeed v1.0.0-beta2
 	github.com/mmcdole/goxpp v0.0.0-20181012175147-0068e33feabf // indirect
 	github.com/open-integration/core v0.65.0
 	github.com/open-integration/service-catalog/google-calendar v0.0.1
 	github.com/open-integration/service-catalog/http v0.0.2
 	github.com/open-integration/service-catalog/jira v0.3.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/smartystreets/goconvey v1.6.4 // indirect
 	github.com/spf13/cobra v0.0.5
+	github.com/stretchr/testify v1.8.4 // indirect
+	github.com/teambition/rrule-go v1.7.2
 	github.com/zealic/xignore v0.3.3 // indirect
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	gopkg.in/hairyhenderson/yaml.v2 v2.0.0-00010101000000-000000000000 // indirect
 	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 	gotest.tools v2.2.0+incompatible // indirect
 )
 
@@ -0,0 +1,110 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/ast"
+	"github.com/antonmedv/expr/parser"
+	"github.com/antonmedv/expr/vm"
+)
+
+type (
+	// names collects the variables and the functions the expression uses
+	names struct {
+		variables map[string]bool
+		functions map[string]bool
+	}
+)
+
+var (
+	mux      sync.Mutex
+	programs = map[string]*vm.Program{}
+)
+
+// IsTemplate returns true when the filter is go template rather than expression
+func IsTemplate(filter string) bool {
+	return strings.Contains(filter, "{{")
+}
+
+// Validate returns error when the expression cannot be compiled, uses variable other than the given ones or its result is not boolean
+// values of the variables are not known at compile time, so type errors of their fields are found only once evaluated
+func Validate(e string, variables []string) error {
+	_, err := compile(e, variables)
+	return err
+}
+
+// Bool evaluates the expression against the values of env, the expression can use only the variables, see Validate
+// the expression must return boolean, any other result is an error
+func Bool(e string, variables []string, env map[string]interface{}) (bool, error) {
+	p, err := compile(e, variables)
+	if err != nil {
+		return false, err
+	}
+	out, err := expr.Run(p, env)
+	if err != nil {
+		return false, err
+	}
+	res, ok := out.(bool)
+	if !ok {
+		return false, fmt.Errorf("Expression returned %T, expected bool", out)
+	}
+	return res, nil
+}
+
+// compile returns the compiled program of the expression, programs are compiled once
+func compile(e string, variables []string) (*vm.Program, error) {
+	mux.Lock()
+	defer mux.Unlock()
+	key := strings.Join(append([]string{e}, variables...), "\x00")
+	if p, ok := programs[key]; ok {
+		return p, nil
+	}
+	// the values depend on the type of the source, e.g. item or issue, so only their names are known at compile time
+	// results of unknown type are accepted and checked once evaluated
+	p, err := expr.Compile(e, expr.AllowUndefinedVariables(), expr.AsBool())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVariables(e, variables); err != nil {
+		return nil, err
+	}
+	programs[key] = p
+	return p, nil
+}
+
+// checkVariables returns error when the expression uses variable other than the given ones, e.g. itme.title
+func checkVariables(e string, variables []string) error {
+	tree, err := parser.Parse(e)
+	if err != nil {
+		return err
+	}
+	n := &names{
+		variables: map[string]bool{},
+		functions: map[string]bool{},
+	}
+	ast.Walk(&tree.Node, n)
+	known := map[string]bool{}
+	for _, v := range variables {
+		known[v] = true
+	}
+	for v := range n.variables {
+		if !known[v] && !n.functions[v] {
+			return fmt.Errorf("Unknown name %s, supported: %s", v, strings.Join(variables, ", "))
+		}
+	}
+	return nil
+}
+
+func (n *names) Visit(node *ast.Node) {
+	switch v := (*node).(type) {
+	case *ast.IdentifierNode:
+		n.variables[v.Value] = true
+	case *ast.CallNode:
+		if f, ok := v.Callee.(*ast.IdentifierNode); ok {
+			n.functions[f.Value] = true
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package expression
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	variables := []string{"item", "event"}
+	tests := []struct {
+		name    string
+		e       string
+		wantErr bool
+	}{
+		{
+			name: "comparison",
+			e:    `item.title contains "Go"`,
+		},
+		{
+			name: "field of unknown type",
+			e:    `item.open == true`,
+		},
+		{
+			name:    "field that is not known to be boolean",
+			e:       `item.open`,
+			wantErr: true,
+		},
+		{
+			name: "time",
+			e:    `date(item.publishedParsed) > now() - duration("24h")`,
+		},
+		{
+			name:    "unknown variable",
+			e:       `itme.title contains "Go"`,
+			wantErr: true,
+		},
+		{
+			name:    "not boolean",
+			e:       `"go"`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax",
+			e:       `item.title ==`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.e, variables); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBool(t *testing.T) {
+	variables := []string{"item"}
+	item := func(published time.Duration) map[string]interface{} {
+		return map[string]interface{}{
+			"item": map[string]interface{}{
+				"title":           "Go 1.14",
+				"publishedParsed": time.Now().Add(-published).Format(time.RFC3339),
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		e       string
+		env     map[string]interface{}
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "new item",
+			e:    `date(item.publishedParsed) > now() - duration("24h")`,
+			env:  item(time.Hour),
+			want: true,
+		},
+		{
+			name: "old item",
+			e:    `date(item.publishedParsed) > now() - duration("24h")`,
+			env:  item(48 * time.Hour),
+			want: false,
+		},
+		{
+			name: "matches",
+			e:    `item.title matches "(?i)\\bgo\\b"`,
+			env:  item(0),
+			want: true,
+		},
+		{
+			name: "variable that is not set",
+			e:    `item == nil`,
+			env:  map[string]interface{}{},
+			want: true,
+		},
+		{
+			name:    "field of other type",
+			e:       `item.title > 1`,
+			env:     item(0),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Bool(tt.e, variables, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Bool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Store keeps track of the items that were already delivered
+	// grouped into buckets, usually bucket per binding
+	Store interface {
+		Exists(bucket string, key string) bool
+		Get(bucket string, key string) (Record, bool)
+		Put(bucket string, key string, record Record)
+		Delete(bucket string, key string)
+		Keys(bucket string) []string
+		Save() error
+	}
+
+	// Record is the data stored for each key
+	Record struct {
+		Created time.Time         `json:"created"`
+		Data    map[string]string `json:"data,omitempty"`
+	}
+
+	fileStore struct {
+		mux      sync.Mutex
+		location string
+		buckets  map[string]map[string]Record
+		// deleted keys are not restored from the file on save
+		deleted map[string]map[string]bool
+	}
+)
+
+// New creates store that is persisted to location
+// when location is empty the store is kept in memory only
+func New(location string) (Store, error) {
+	s := &fileStore{
+		location: location,
+		buckets:  map[string]map[string]Record{},
+		deleted:  map[string]map[string]bool{},
+	}
+	if location == "" {
+		return s, nil
+	}
+	buckets, err := load(location)
+	if err != nil {
+		return nil, err
+	}
+	s.buckets = buckets
+	return s, nil
+}
+
+func (s *fileStore) Exists(bucket string, key string) bool {
+	_, ok := s.Get(bucket, key)
+	return ok
+}
+
+func (s *fileStore) Get(bucket string, key string) (Record, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	r, ok := s.buckets[bucket][key]
+	return r, ok
+}
+
+func (s *fileStore) Put(bucket string, key string, record Record) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if record.Created.IsZero() {
+		record.Created = time.Now()
+	}
+	if _, ok := s.buckets[bucket]; !ok {
+		s.buckets[bucket] = map[string]Record{}
+	}
+	s.buckets[bucket][key] = record
+	delete(s.deleted[bucket], key)
+}
+
+func (s *fileStore) Delete(bucket string, key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.buckets[bucket], key)
+	if _, ok := s.deleted[bucket]; !ok {
+		s.deleted[bucket] = map[string]bool{}
+	}
+	s.deleted[bucket][key] = true
+}
+
+// Keys returns the sorted keys of the bucket
+func (s *fileStore) Keys(bucket string) []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	keys := []string{}
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Save writes the store to the file
+// records that were written to the file by another process since it was loaded are kept
+func (s *fileStore) Save() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.location == "" {
+		return nil
+	}
+	current, err := load(s.location)
+	if err != nil {
+		return err
+	}
+	for bucket, records := range current {
+		if _, ok := s.buckets[bucket]; !ok {
+			s.buckets[bucket] = map[string]Record{}
+		}
+		for key, r := range records {
+			if s.deleted[bucket][key] {
+				continue
+			}
+			if _, ok := s.buckets[bucket][key]; !ok {
+				s.buckets[bucket][key] = r
+			}
+		}
+	}
+	b, err := json.MarshalIndent(s.buckets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.location), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := s.location + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.location)
+}
+
+func load(location string) (map[string]map[string]Record, error) {
+	buckets := map[string]map[string]Record{}
+	b, err := ioutil.ReadFile(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return buckets, nil
+		}
+		return nil, err
+	}
+	if len(b) == 0 {
+		return buckets, nil
+	}
+	if err := json.Unmarshal(b, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
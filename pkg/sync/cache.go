@@ -0,0 +1,204 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/open-integration/service-catalog/http/pkg/endpoints/call"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// sourceStateBucket keeps the state returned by the last fetch of each binding
+	sourceStateBucket = "_sources"
+
+	storeKeyFingerprint = "_fingerprint"
+
+	fileURLPrefix = "file://"
+
+	// keys of the state of sources fetched with conditional request
+	stateKeyURL          = "url"
+	stateKeyETag         = "etag"
+	stateKeyLastModified = "last-modified"
+)
+
+func conditionalGetEnabled(src Source) bool {
+	return src.ConditionalGet == nil || *src.ConditionalGet
+}
+
+// bindingFingerprint hashes the config of the binding, its source and its targets
+func bindingFingerprint(tcs []taskCandidate) string {
+	fp := struct {
+		Binding Binding
+		Source  Source
+		Target  Target
+		// Targets are the other targets of binding with targets
+		Targets []Target `yaml:",omitempty"`
+	}{
+		Binding: tcs[0].binding,
+		Source:  tcs[0].src,
+		Target:  tcs[0].target,
+	}
+	for _, tc := range tcs[1:] {
+		fp.Targets = append(fp.Targets, tc.target)
+	}
+	b, err := yaml.Marshal(fp)
+	if err != nil {
+		return ""
+	}
+	h := sha1.Sum(b)
+	return hex.EncodeToString(h[:])
+}
+
+// fetchURL sends GET request to the url using the http service
+// when conditional is set the validators found in the state are sent
+// and the validators of the response are returned as the new state
+// file:// urls are read from the disk, e.g. file:///tmp/feed.xml or file://feed.xml relative to the working directory
+func fetchURL(ctx context.Context, req FetchRequest, u string, conditional bool) (call.CallReturns, map[string]string, error) {
+	if strings.HasPrefix(u, fileURLPrefix) {
+		name := strings.TrimPrefix(u, fileURLPrefix)
+		// buildURL escapes the path
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return call.CallReturns{}, nil, err
+		}
+		return call.CallReturns{
+			Body: string(b),
+		}, nil, nil
+	}
+	// the http service of the open-integration engine returns all the headers of the response with the name of one of them
+	// the validators cannot be told apart, conditional requests are sent by the native engine only
+	if _, ok := req.Caller.(*nativeCaller); !ok {
+		conditional = false
+	}
+	c := httpCall{
+		URL:     u,
+		Method:  "GET",
+		Headers: map[string]string{},
+	}
+	if conditional && req.State[stateKeyURL] == u {
+		if etag := req.State[stateKeyETag]; etag != "" {
+			c.Headers["If-None-Match"] = etag
+		}
+		if lm := req.State[stateKeyLastModified]; lm != "" {
+			c.Headers["If-Modified-Since"] = lm
+		}
+	}
+	res := call.CallReturns{}
+	if err := callService(ctx, req.Caller, req.FD, "http", "call", httpCallArguments(c), &res); err != nil {
+		return res, nil, err
+	}
+	if !conditional {
+		return res, nil, nil
+	}
+	if res.Status == http.StatusNotModified {
+		return res, req.State, nil
+	}
+	st := map[string]string{}
+	if etag := responseHeader(res, "ETag"); etag != "" {
+		st[stateKeyETag] = etag
+	}
+	if lm := responseHeader(res, "Last-Modified"); lm != "" {
+		st[stateKeyLastModified] = lm
+	}
+	if len(st) > 0 {
+		st[stateKeyURL] = u
+	}
+	return res, st, nil
+}
+
+// responseHeader returns the value of the header of the response, the names are compared case insensitively
+func responseHeader(res call.CallReturns, name string) string {
+	for _, h := range res.Headers {
+		if h.Name != nil && h.Value != nil && strings.EqualFold(*h.Name, name) {
+			return *h.Value
+		}
+	}
+	return ""
+}
+
+// readHTTPResponse returns error on unsuccessful response status
+func readHTTPResponse(res call.CallReturns) error {
+	if res.Status >= 400 {
+		return fmt.Errorf("Request failed with status %d", res.Status)
+	}
+	return nil
+}
+
+func notModified(res call.CallReturns) bool {
+	return res.Status == http.StatusNotModified
+}
+
+// sourceState returns the state of the last fetch of the binding
+// the state is ignored once the config of the binding was changed
+func (d *deliveryTracker) sourceState(tcs []taskCandidate) map[string]string {
+	tc := tcs[0]
+	fingerprint := bindingFingerprint(tcs)
+	d.mux.Lock()
+	d.fingerprints[tc.binding.Name] = fingerprint
+	d.mux.Unlock()
+	r, ok := d.store.Get(sourceStateBucket, tc.binding.Name)
+	if !ok || r.Data[storeKeyFingerprint] != fingerprint {
+		return map[string]string{}
+	}
+	st := map[string]string{}
+	for k, v := range r.Data {
+		if k != storeKeyFingerprint {
+			st[k] = v
+		}
+	}
+	return st
+}
+
+// keepSourceState keeps the state returned by the fetch, it is stored once the binding finished successfully
+func (d *deliveryTracker) keepSourceState(tc taskCandidate, st map[string]string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.sourceStates[tc.binding.Name] = st
+}
+
+// saveSourceStates stores the state of the sources of the bindings that succeeded
+// the state of failed bindings is not stored so the items are fetched again on the next run
+func (d *deliveryTracker) saveSourceStates() {
+	if d.planner != nil {
+		return
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if len(d.sourceStates) == 0 {
+		return
+	}
+	for binding, st := range d.sourceStates {
+		if !d.report.succeeded(binding) {
+			continue
+		}
+		if len(st) == 0 {
+			d.store.Delete(sourceStateBucket, binding)
+			continue
+		}
+		data := map[string]string{
+			storeKeyFingerprint: d.fingerprints[binding],
+		}
+		for k, v := range st {
+			data[k] = v
+		}
+		d.store.Put(sourceStateBucket, binding, store.Record{
+			Data: data,
+		})
+	}
+	if err := d.store.Save(); err != nil {
+		for binding := range d.sourceStates {
+			d.report.fail(binding, fmt.Errorf("Failed to save state: %w", err))
+		}
+	}
+}
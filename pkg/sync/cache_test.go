@@ -0,0 +1,159 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// callerFunc is ServiceCaller of the open-integration engine in tests
+type callerFunc func(ctx context.Context, service string, endpoint string, arguments map[string]interface{}, fd string) ([]byte, error)
+
+func (f callerFunc) Call(ctx context.Context, service string, endpoint string, arguments map[string]interface{}, fd string) ([]byte, error) {
+	return f(ctx, service, endpoint, arguments, fd)
+}
+
+func TestFetchURLConditional(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 12 Oct 2026 08:00:00 GMT"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("<rss></rss>"))
+	}))
+	defer srv.Close()
+	native := &nativeCaller{client: srv.Client()}
+	validators := map[string]string{
+		stateKeyURL:          srv.URL,
+		stateKeyETag:         etag,
+		stateKeyLastModified: lastModified,
+	}
+	tests := []struct {
+		name        string
+		caller      ServiceCaller
+		state       map[string]string
+		conditional bool
+		wantStatus  int
+		wantState   map[string]string
+	}{
+		{
+			name:        "first fetch stores the validators",
+			caller:      native,
+			state:       map[string]string{},
+			conditional: true,
+			wantStatus:  http.StatusOK,
+			wantState:   validators,
+		},
+		{
+			name:        "not modified keeps the validators",
+			caller:      native,
+			state:       validators,
+			conditional: true,
+			wantStatus:  http.StatusNotModified,
+			wantState:   validators,
+		},
+		{
+			name:   "validators of other url are not sent",
+			caller: native,
+			state: map[string]string{
+				stateKeyURL:  srv.URL + "/other",
+				stateKeyETag: etag,
+			},
+			conditional: true,
+			wantStatus:  http.StatusOK,
+			wantState:   validators,
+		},
+		{
+			name:        "conditional get disabled",
+			caller:      native,
+			state:       validators,
+			conditional: false,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name: "open-integration engine",
+			caller: callerFunc(func(ctx context.Context, service string, endpoint string, arguments map[string]interface{}, fd string) ([]byte, error) {
+				return native.Call(ctx, service, endpoint, arguments, fd)
+			}),
+			state:       validators,
+			conditional: true,
+			wantStatus:  http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := FetchRequest{
+				Caller: tt.caller,
+				State:  tt.state,
+			}
+			res, st, err := fetchURL(context.Background(), req, srv.URL, tt.conditional)
+			if err != nil {
+				t.Fatalf("fetchURL() error = %v", err)
+			}
+			if res.Status != tt.wantStatus {
+				t.Errorf("fetchURL() status = %d, want %d", res.Status, tt.wantStatus)
+			}
+			if len(st) != 0 || len(tt.wantState) != 0 {
+				if !reflect.DeepEqual(st, tt.wantState) {
+					t.Errorf("fetchURL() state = %v, want %v", st, tt.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestRSSSourceNotModified(t *testing.T) {
+	const etag = `"v1"`
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`<rss version="2.0"><channel><title>feed</title><item><guid>1</guid><title>Episode 1</title></item></channel></rss>`))
+	}))
+	defer srv.Close()
+	src := Source{}
+	if err := yaml.Unmarshal([]byte("name: feed\nrss:\n  url: "+srv.URL), &src); err != nil {
+		t.Fatal(err)
+	}
+	kind, err := buildSourceKind(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := FetchRequest{
+		Caller: &nativeCaller{client: srv.Client()},
+		State:  map[string]string{},
+	}
+	first, err := kind.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(first.Items) != 1 || first.NotModified || first.State[stateKeyETag] != etag {
+		t.Fatalf("Fetch() = %+v", first)
+	}
+	req.State = first.State
+	second, err := kind.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(second.Items) != 0 || !second.NotModified || !reflect.DeepEqual(second.State, first.State) {
+		t.Errorf("Fetch() = %+v", second)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
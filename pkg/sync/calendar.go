@@ -0,0 +1,211 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/open-integration/core/pkg/task"
+	"github.com/open-integration/service-catalog/google-calendar/pkg/endpoints/getEvents"
+)
+
+const (
+	googleCalendarStatusCancelled = "cancelled"
+)
+
+type (
+	googleCalendarSource struct {
+		src Source
+	}
+
+	googleCalendarGetEventsOptions struct {
+		ServiceAccount          getEvents.ServiceAccount
+		CalendarID              string
+		ICalUID                 *string
+		MaxAttendees            *int64
+		MaxResults              *int64
+		OrderBy                 *string
+		PrivateExtendedProperty *string
+		Q                       *string
+		SharedExtendedProperty  *string
+		ShowDeleted             bool
+		ShowHiddenInvitations   *bool
+		SingleEvents            bool
+		TimeMax                 string
+		TimeMin                 string
+		TimeZone                *string
+		UpdatedMin              *string
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindGoogleCalendar, func(src Source) (SourceKind, error) {
+		if src.GoogleCalendar == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no google-calendar config", src.Name)
+		}
+		return &googleCalendarSource{src: src}, nil
+	})
+}
+
+// Fetch returns the events between time-min and time-max with "event" data
+func (s *googleCalendarSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	f, err := ioutil.ReadFile(template.String(&s.src.GoogleCalendar.ServiceAccount, nil))
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Faild to read service-account file: %w", err)
+	}
+	sa := getEvents.ServiceAccount{}
+	if err := json.Unmarshal(f, &sa); err != nil {
+		return FetchResult{}, fmt.Errorf("Faild to read service-account file: %w", err)
+	}
+	res := getEvents.GetEventsReturns{}
+	if err := callService(ctx, req.Caller, req.FD, "google-calendar", "getEvents", googleCalendarGetEventsArguments(googleCalendarGetEventsOptions{
+		ServiceAccount: sa,
+		CalendarID:     template.String(&s.src.GoogleCalendar.CalendarID, nil),
+		TimeMin:        template.String(&s.src.GoogleCalendar.TimeMin, nil),
+		TimeMax:        template.String(&s.src.GoogleCalendar.TimeMax, nil),
+		ShowDeleted:    true,
+		SingleEvents:   true,
+	}), &res); err != nil {
+		return FetchResult{}, err
+	}
+	// the events are the ones of the time window and of the first page of the results, events that end are not removed
+	// deleted events are returned as cancelled
+	result := FetchResult{
+		Partial: true,
+	}
+	for _, event := range res.Events {
+		result.Items = append(result.Items, Item{
+			Key: googleCalendarEventKey(event),
+			Data: map[string]interface{}{
+				"event": googleCalendarEventToJSON(event),
+			},
+			Meta:    googleCalendarEventMeta(event),
+			Removed: event.Status != nil && *event.Status == googleCalendarStatusCancelled,
+		})
+	}
+	return result, nil
+}
+
+func googleCalendarGetEventsArguments(options googleCalendarGetEventsOptions) []task.Argument {
+	arguments := []task.Argument{
+		{
+			Key:   "ServiceAccount",
+			Value: options.ServiceAccount,
+		},
+		{
+			Key:   "CalendarID",
+			Value: options.CalendarID,
+		},
+		{
+			Key:   "ShowDeleted",
+			Value: options.ShowDeleted,
+		},
+		{
+			Key:   "SingleEvents",
+			Value: options.SingleEvents,
+		},
+	}
+
+	if options.ICalUID != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "ICalUID",
+			Value: *options.ICalUID,
+		})
+	}
+
+	if options.MaxAttendees != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "MaxAttendees",
+			Value: *options.MaxAttendees,
+		})
+	}
+	if options.MaxResults != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "MaxResults",
+			Value: *options.MaxResults,
+		})
+	}
+	if options.OrderBy != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "OrderBy",
+			Value: *options.OrderBy,
+		})
+	}
+	if options.PrivateExtendedProperty != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "PrivateExtendedProperty",
+			Value: *options.PrivateExtendedProperty,
+		})
+	}
+	if options.Q != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "Q",
+			Value: *options.Q,
+		})
+	}
+	if options.SharedExtendedProperty != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "SharedExtendedProperty",
+			Value: *options.SharedExtendedProperty,
+		})
+	}
+	if options.ShowHiddenInvitations != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "ShowHiddenInvitations",
+			Value: *options.ShowHiddenInvitations,
+		})
+	}
+	if options.TimeMax != "" {
+		arguments = append(arguments, task.Argument{
+			Key:   "TimeMax",
+			Value: options.TimeMax,
+		})
+	}
+	if options.TimeMin != "" {
+		arguments = append(arguments, task.Argument{
+			Key:   "TimeMin",
+			Value: options.TimeMin,
+		})
+	}
+	if options.TimeZone != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "TimeZone",
+			Value: *options.TimeZone,
+		})
+	}
+	if options.UpdatedMin != nil {
+		arguments = append(arguments, task.Argument{
+			Key:   "UpdatedMin",
+			Value: *options.UpdatedMin,
+		})
+	}
+	return arguments
+}
+
+func googleCalendarEventMeta(ev getEvents.Event) ItemMeta {
+	meta := ItemMeta{
+		GUID: googleCalendarEventKey(ev),
+	}
+	if ev.HTMLLink != nil {
+		meta.Link = *ev.HTMLLink
+	}
+	if ev.Summary != nil {
+		meta.Title = *ev.Summary
+	}
+	if ev.Created != nil {
+		if t, err := time.Parse(time.RFC3339, *ev.Created); err == nil {
+			meta.Published = &t
+		}
+	}
+	if ev.Creator != nil {
+		for _, a := range []*string{ev.Creator.DisplayName, ev.Creator.Email} {
+			if a != nil && *a != "" {
+				meta.Authors = append(meta.Authors, *a)
+			}
+		}
+	}
+	return meta
+}
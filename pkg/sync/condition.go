@@ -1,17 +1,22 @@
-package cmd
+package sync
 
 import (
+	gosync "sync"
+
 	"github.com/open-integration/core/pkg/event"
 	"github.com/open-integration/core/pkg/state"
 )
 
 type (
-	TaskFinished struct {
+	taskFinished struct {
+		mux         gosync.Mutex
 		followTasks []string
 	}
 )
 
-func (c *TaskFinished) Met(ev event.Event, s state.State) bool {
+func (c *taskFinished) Met(ev event.Event, s state.State) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
 	met := false
 	for _, t := range c.followTasks {
 		if t == ev.Metadata.Task {
@@ -24,6 +29,8 @@ func (c *TaskFinished) Met(ev event.Event, s state.State) bool {
 	return ev.Metadata.Name == state.EventTaskFinished
 }
 
-func (c *TaskFinished) AddTask(name string) {
+func (c *taskFinished) AddTask(name string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
 	c.followTasks = append(c.followTasks, name)
 }
@@ -0,0 +1,295 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	gosync "sync"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/open-integration/core"
+	"github.com/open-integration/core/pkg/event"
+	"github.com/open-integration/core/pkg/state"
+	"github.com/open-integration/core/pkg/task"
+)
+
+type (
+	// deliveryTracker follows the tasks that deliver items to targets
+	// and records the delivered items in the store once the task finished successfully
+	deliveryTracker struct {
+		taskFinished
+		mux     gosync.Mutex
+		store   store.Store
+		planner *planner
+		report  *report
+		// ctx is the context of the run the tasks run with
+		ctx context.Context
+		// caller is used by the tasks instead of the modem of the engine when set
+		caller     ServiceCaller
+		httpClient *http.Client
+		deliveries map[string]delivery
+		// results of the fetch tasks by task name
+		fetches map[string]FetchResult
+		// fingerprints and states of the sources by binding
+		fingerprints map[string]string
+		sourceStates map[string]map[string]string
+	}
+
+	delivery struct {
+		binding string
+		// bucket the item is stored in, see taskCandidate.bucket
+		bucket string
+		key    string
+		// previous is the record stored when the item was delivered before
+		previous *store.Record
+		// removed is set when the task acts on item that was removed from the source
+		// the item is deleted from the store once the task finished successfully
+		removed bool
+		// digest holds the keys of the items delivered together by the task
+		digest []string
+	}
+)
+
+func newDeliveryTracker(ctx context.Context, s store.Store, p *planner, r *report, caller ServiceCaller, client *http.Client) *deliveryTracker {
+	return &deliveryTracker{
+		ctx:          ctx,
+		store:        s,
+		planner:      p,
+		report:       r,
+		caller:       caller,
+		httpClient:   client,
+		deliveries:   map[string]delivery{},
+		fetches:      map[string]FetchResult{},
+		fingerprints: map[string]string{},
+		sourceStates: map[string]map[string]string{},
+	}
+}
+
+// serviceCaller returns the caller the task calls the services with
+func (d *deliveryTracker) serviceCaller(opt task.RunOptions) ServiceCaller {
+	if d.caller != nil {
+		return d.caller
+	}
+	return opt.Modem
+}
+
+// newTask returns task that runs with the context of the run
+// the engine runs the tasks with context that is never cancelled
+func (d *deliveryTracker) newTask(name string, fn func(ctx context.Context, opt task.RunOptions) ([]byte, error)) task.Task {
+	return core.NewFunctionTask(name, func(_ context.Context, opt task.RunOptions) ([]byte, error) {
+		return fn(d.ctx, opt)
+	})
+}
+
+// fetched keeps the result of the fetch task
+func (d *deliveryTracker) fetched(taskName string, res FetchResult) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.fetches[taskName] = res
+}
+
+func (d *deliveryTracker) fetchResult(taskName string) FetchResult {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.fetches[taskName]
+}
+
+// deliver returns the task that delivers the item to the target
+// items the target has nothing to do with are skipped
+// on dry run the item is added to the plan and no task is returned
+func (d *deliveryTracker) deliver(taskName string, tc taskCandidate, data interface{}, key string) []task.Task {
+	d.report.matched(tc.binding.Name)
+	if d.pending(tc.bucket(), key) {
+		d.report.skipped(tc.binding.Name)
+		return nil
+	}
+	kind, err := buildTargetKind(tc.target)
+	if err != nil {
+		d.report.fail(tc.binding.Name, err)
+		return nil
+	}
+	req := DeliveryRequest{
+		Key:      key,
+		Data:     data,
+		Previous: d.previous(tc.bucket(), key),
+	}
+	p, err := kind.Plan(req)
+	if err != nil {
+		d.report.deliveryFailed(tc.binding.Name, key, err)
+		return nil
+	}
+	if p.Action == "" {
+		d.report.skipped(tc.binding.Name)
+		return nil
+	}
+	if d.planner != nil {
+		d.planner.add(tc, key, p)
+		return nil
+	}
+	d.track(taskName, delivery{
+		binding:  tc.binding.Name,
+		bucket:   tc.bucket(),
+		key:      key,
+		previous: req.Previous,
+	})
+	return []task.Task{d.newTask(taskName, func(ctx context.Context, opt task.RunOptions) ([]byte, error) {
+		req.Caller = d.serviceCaller(opt)
+		req.HTTPClient = d.httpClient
+		req.FD = opt.FD.File()
+		data, err := kind.Deliver(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(data)
+	})}
+}
+
+// reconcile returns the tasks that apply the on-removed action of the binding on the items that were delivered before
+// and were removed from the source, or were not seen in the latest fetch when the fetch returned all the items of the source
+func (d *deliveryTracker) reconcile(tc taskCandidate, seen map[string]bool, removed map[string]bool, partial bool) []task.Task {
+	if tc.binding.OnRemoved == nil {
+		return nil
+	}
+	kind, err := buildTargetKind(tc.target)
+	if err != nil {
+		d.report.fail(tc.binding.Name, err)
+		return nil
+	}
+	remover, ok := kind.(Remover)
+	if !ok {
+		return nil
+	}
+	onRemoved := *tc.binding.OnRemoved
+	tasks := []task.Task{}
+	for _, key := range d.store.Keys(tc.bucket()) {
+		if !removed[key] && (partial || seen[key]) {
+			continue
+		}
+		data := buildValues(tc)
+		data.Add("key", key)
+		req := DeliveryRequest{
+			Key:      key,
+			Data:     data,
+			Previous: d.previous(tc.bucket(), key),
+		}
+		p, err := remover.PlanRemove(req, onRemoved)
+		if err != nil {
+			d.report.deliveryFailed(tc.binding.Name, key, err)
+			continue
+		}
+		if p.Action == "" {
+			continue
+		}
+		if d.planner != nil {
+			d.planner.add(tc, key, p)
+			continue
+		}
+		taskName := fmt.Sprintf("%s-removed-%s", p.Action, hashKey(tc.bucket(), key))
+		d.track(taskName, delivery{
+			binding: tc.binding.Name,
+			bucket:  tc.bucket(),
+			key:     key,
+			removed: true,
+		})
+		tasks = append(tasks, d.newTask(taskName, func(ctx context.Context, opt task.RunOptions) ([]byte, error) {
+			req.Caller = d.serviceCaller(opt)
+			req.HTTPClient = d.httpClient
+			req.FD = opt.FD.File()
+			return nil, remover.Remove(ctx, req, onRemoved)
+		}))
+	}
+	return tasks
+}
+
+// previous returns the record stored when the item was delivered to the bucket before
+func (d *deliveryTracker) previous(bucket string, key string) *store.Record {
+	if key == "" {
+		return nil
+	}
+	r, ok := d.store.Get(bucket, key)
+	if !ok {
+		return nil
+	}
+	return &r
+}
+
+// pending returns true when the item is going to be delivered by a task that was already created
+func (d *deliveryTracker) pending(bucket string, key string) bool {
+	if key == "" {
+		return false
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	for _, dl := range d.deliveries {
+		if dl.bucket == bucket && dl.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// track starts to follow the task that delivers the item
+func (d *deliveryTracker) track(taskName string, dl delivery) {
+	d.mux.Lock()
+	d.deliveries[taskName] = dl
+	d.mux.Unlock()
+	d.AddTask(taskName)
+}
+
+func (d *deliveryTracker) reactToCompletedTask(ev event.Event, s state.State) []task.Task {
+	d.mux.Lock()
+	dl, ok := d.deliveries[ev.Metadata.Task]
+	d.mux.Unlock()
+	if !ok {
+		return nil
+	}
+	t := s.Tasks()[ev.Metadata.Task]
+	if t.Status != state.TaskStatusSuccess {
+		err := t.Error
+		if err == nil {
+			err = fmt.Errorf("Task %s failed", ev.Metadata.Task)
+		}
+		d.report.deliveryFailed(dl.binding, dl.key, err)
+		return nil
+	}
+	if dl.digest != nil {
+		d.digestDelivered(dl)
+		return nil
+	}
+	if dl.removed {
+		d.report.removed(dl.binding)
+		d.store.Delete(dl.bucket, dl.key)
+		if err := d.store.Save(); err != nil {
+			d.report.fail(dl.binding, fmt.Errorf("Failed to save state: %w", err))
+		}
+		return nil
+	}
+	d.report.delivered(dl.binding)
+	if dl.key == "" {
+		return nil
+	}
+	record := store.Record{}
+	if dl.previous != nil {
+		record.Created = dl.previous.Created
+	}
+	if len(t.Output) > 0 {
+		if err := json.Unmarshal(t.Output, &record.Data); err != nil {
+			d.report.fail(dl.binding, fmt.Errorf("Failed to read output of task %s: %w", ev.Metadata.Task, err))
+			return nil
+		}
+	}
+	d.store.Put(dl.bucket, dl.key, record)
+	if err := d.store.Save(); err != nil {
+		d.report.fail(dl.binding, fmt.Errorf("Failed to save state: %w", err))
+	}
+	return nil
+}
+
+// hashKey returns short hash of the bucket and the key of the item, safe to be used in task names
+func hashKey(bucket string, key string) string {
+	h := sha1.Sum([]byte(bucket + seperator + key))
+	return hex.EncodeToString(h[:])[:12]
+}
@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/open-integration/core/pkg/task"
+)
+
+const (
+	digestWindowRun    = "run"
+	digestWindowDaily  = "daily"
+	digestWindowWeekly = "weekly"
+
+	// digestBucketPrefix prefixes the bucket the items waiting for the window to end are kept in
+	digestBucketPrefix = "_digest"
+
+	recordKeyWindow = "window"
+	recordKeyData   = "data"
+)
+
+type (
+	// digestEntry is an item that passed the filters of binding in digest mode
+	digestEntry struct {
+		key  string
+		data map[string]interface{}
+	}
+)
+
+func digestWindow(binding Binding) string {
+	if binding.Digest == nil || binding.Digest.Window == "" {
+		return digestWindowRun
+	}
+	return binding.Digest.Window
+}
+
+// digestWindowID returns the id of the window the time is in, e.g. 2020-06-01 or 2020-W23
+func digestWindowID(window string, t time.Time) string {
+	switch window {
+	case digestWindowDaily:
+		return t.Format("2006-01-02")
+	case digestWindowWeekly:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	}
+	return ""
+}
+
+// digestBucket returns the bucket the items of the bucket are buffered in until the window ends
+func digestBucket(bucket string) string {
+	return fmt.Sprintf("%s%s%s", digestBucketPrefix, seperator, bucket)
+}
+
+// deliverDigest returns the task that delivers the items as one digest
+// items of daily and weekly windows are kept in the store and delivered by the first run after the window ended
+func (d *deliveryTracker) deliverDigest(tc taskCandidate, entries []digestEntry) []task.Task {
+	binding := tc.binding.Name
+	window := digestWindow(tc.binding)
+	ready := entries
+	if window != digestWindowRun {
+		ready = d.bufferDigest(tc, digestWindowID(window, time.Now()), entries)
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	kind, err := buildTargetKind(tc.target)
+	if err != nil {
+		d.report.fail(binding, err)
+		return nil
+	}
+	items := []interface{}{}
+	keys := []string{}
+	for _, e := range ready {
+		items = append(items, e.data)
+		keys = append(keys, e.key)
+	}
+	data := buildValues(tc)
+	data.Add("items", items)
+	data.Add("count", len(items))
+	req := DeliveryRequest{
+		Data: data,
+	}
+	p, err := kind.Plan(req)
+	if err != nil {
+		d.report.deliveryFailed(binding, "digest", err)
+		return nil
+	}
+	if p.Action == "" {
+		return nil
+	}
+	if d.planner != nil {
+		d.planner.add(tc, "digest", p)
+		return nil
+	}
+	taskName := fmt.Sprintf("digest-%s", hashKey(tc.bucket(), fmt.Sprintf("%v", keys)))
+	d.track(taskName, delivery{
+		binding: binding,
+		bucket:  tc.bucket(),
+		key:     "digest",
+		digest:  keys,
+	})
+	return []task.Task{d.newTask(taskName, func(ctx context.Context, opt task.RunOptions) ([]byte, error) {
+		req.Caller = d.serviceCaller(opt)
+		req.HTTPClient = d.httpClient
+		req.FD = opt.FD.File()
+		_, err := kind.Deliver(ctx, req)
+		return nil, err
+	})}
+}
+
+// bufferDigest keeps the entries in the window they were first seen at
+// and returns the entries of the windows that already ended
+func (d *deliveryTracker) bufferDigest(tc taskCandidate, current string, entries []digestEntry) []digestEntry {
+	binding := tc.binding.Name
+	bucket := digestBucket(tc.bucket())
+	if d.planner == nil {
+		for _, e := range entries {
+			if d.store.Exists(bucket, e.key) {
+				continue
+			}
+			b, err := json.Marshal(e.data)
+			if err != nil {
+				d.report.deliveryFailed(binding, e.key, err)
+				continue
+			}
+			d.store.Put(bucket, e.key, store.Record{
+				Data: map[string]string{
+					recordKeyWindow: current,
+					recordKeyData:   string(b),
+				},
+			})
+		}
+		if err := d.store.Save(); err != nil {
+			d.report.fail(binding, fmt.Errorf("Failed to save state: %w", err))
+		}
+	}
+	type buffered struct {
+		digestEntry
+		created time.Time
+	}
+	ended := []buffered{}
+	for _, key := range d.store.Keys(bucket) {
+		r, _ := d.store.Get(bucket, key)
+		if r.Data[recordKeyWindow] == current {
+			continue
+		}
+		data := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(r.Data[recordKeyData]), &data); err != nil {
+			d.report.deliveryFailed(binding, key, err)
+			continue
+		}
+		ended = append(ended, buffered{
+			digestEntry: digestEntry{
+				key:  key,
+				data: data,
+			},
+			created: r.Created,
+		})
+	}
+	sort.SliceStable(ended, func(i, j int) bool {
+		return ended[i].created.Before(ended[j].created)
+	})
+	res := []digestEntry{}
+	for _, e := range ended {
+		res = append(res, e.digestEntry)
+	}
+	return res
+}
+
+// digestDelivered records the items of the digest as delivered
+func (d *deliveryTracker) digestDelivered(dl delivery) {
+	bucket := digestBucket(dl.bucket)
+	for _, key := range dl.digest {
+		d.report.delivered(dl.binding)
+		d.store.Delete(bucket, key)
+		if key != "" {
+			d.store.Put(dl.bucket, key, store.Record{})
+		}
+	}
+	if err := d.store.Save(); err != nil {
+		d.report.fail(dl.binding, fmt.Errorf("Failed to save state: %w", err))
+	}
+}
@@ -0,0 +1,262 @@
+package sync
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDigestWindowID(t *testing.T) {
+	tests := []struct {
+		name   string
+		window string
+		t      time.Time
+		want   string
+	}{
+		{
+			name:   "run",
+			window: digestWindowRun,
+			t:      time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			want:   "",
+		},
+		{
+			name:   "daily",
+			window: digestWindowDaily,
+			t:      time.Date(2020, 6, 1, 23, 59, 0, 0, time.UTC),
+			want:   "2020-06-01",
+		},
+		{
+			name:   "weekly",
+			window: digestWindowWeekly,
+			t:      time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			want:   "2020-W23",
+		},
+		{
+			name:   "weekly on sunday is the week that started on monday",
+			window: digestWindowWeekly,
+			t:      time.Date(2020, 6, 7, 10, 0, 0, 0, time.UTC),
+			want:   "2020-W23",
+		},
+		{
+			name:   "weekly of the first days of the year in the last week of the previous year",
+			window: digestWindowWeekly,
+			t:      time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC),
+			want:   "2020-W53",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestWindowID(tt.window, tt.t); got != tt.want {
+				t.Errorf("digestWindowID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		binding Binding
+		want    string
+	}{
+		{
+			name:    "no digest",
+			binding: Binding{},
+			want:    digestWindowRun,
+		},
+		{
+			name:    "digest without window",
+			binding: Binding{Digest: &Digest{}},
+			want:    digestWindowRun,
+		},
+		{
+			name:    "digest with window",
+			binding: Binding{Digest: &Digest{Window: digestWindowWeekly}},
+			want:    digestWindowWeekly,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestWindow(tt.binding); got != tt.want {
+				t.Errorf("digestWindow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBufferDigest(t *testing.T) {
+	s, err := store.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := taskCandidate{
+		binding: Binding{Name: "b", Digest: &Digest{Window: digestWindowDaily}},
+	}
+	d := newDeliveryTracker(context.Background(), s, nil, newReport(Sync{Name: "test"}, discardLogger{}), nil, nil)
+	entries := func(keys ...string) []digestEntry {
+		res := []digestEntry{}
+		for _, k := range keys {
+			res = append(res, digestEntry{key: k, data: map[string]interface{}{"title": k}})
+		}
+		return res
+	}
+	steps := []struct {
+		name    string
+		window  string
+		entries []digestEntry
+		// delivered is set when the ended entries are delivered
+		delivered bool
+		want      []string
+	}{
+		{
+			name:    "entries are kept until the window ends",
+			window:  "2020-06-01",
+			entries: entries("a", "b"),
+			want:    []string{},
+		},
+		{
+			name:    "entries of the same window are added",
+			window:  "2020-06-01",
+			entries: entries("b", "c"),
+			want:    []string{},
+		},
+		{
+			name:    "entries of the window that ended are returned",
+			window:  "2020-06-02",
+			entries: entries("a", "d"),
+			want:    []string{"a", "b", "c"},
+		},
+		{
+			name:      "entries that were not delivered are returned again",
+			window:    "2020-06-02",
+			entries:   entries(),
+			delivered: true,
+			want:      []string{"a", "b", "c"},
+		},
+		{
+			name:    "delivered entries are not returned",
+			window:  "2020-06-02",
+			entries: entries(),
+			want:    []string{},
+		},
+		{
+			name:      "entry first seen in the previous window",
+			window:    "2020-06-03",
+			entries:   entries("e"),
+			delivered: true,
+			want:      []string{"d"},
+		},
+	}
+	for _, step := range steps {
+		ready := d.bufferDigest(tc, step.window, step.entries)
+		got := []string{}
+		for _, e := range ready {
+			if e.data["title"] != e.key {
+				t.Errorf("%s: data of %s = %v", step.name, e.key, e.data)
+			}
+			got = append(got, e.key)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: bufferDigest() = %v, want %v", step.name, got, step.want)
+		}
+		if step.delivered {
+			d.digestDelivered(delivery{
+				binding: tc.binding.Name,
+				bucket:  tc.bucket(),
+				digest:  got,
+			})
+		}
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if !s.Exists(tc.bucket(), key) {
+			t.Errorf("delivered entry %s is not stored", key)
+		}
+	}
+}
+
+func TestBufferDigestPlan(t *testing.T) {
+	s, err := store.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := taskCandidate{
+		binding: Binding{Name: "b", Digest: &Digest{Window: digestWindowDaily}},
+	}
+	d := newDeliveryTracker(context.Background(), s, &planner{}, newReport(Sync{Name: "test"}, discardLogger{}), nil, nil)
+	d.bufferDigest(tc, "2020-06-01", []digestEntry{{key: "a", data: map[string]interface{}{}}})
+	if got := s.Keys(digestBucket(tc.bucket())); len(got) != 0 {
+		t.Errorf("plan buffered %v, want nothing", got)
+	}
+}
+
+func TestDeliverFetchResultNotModified(t *testing.T) {
+	cnf := Sync{}
+	err := yaml.Unmarshal([]byte(`
+sources:
+- name: s
+  rss:
+    url: http://localhost/feed
+targets:
+- name: w
+  webhook:
+    url: http://localhost/hook
+    body: '{{ .count }}'
+bindings:
+- name: b
+  source: s
+  target: w
+  digest:
+    window: daily
+`), &cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		// window the buffered item was seen at, nothing is buffered when empty
+		window string
+		want   int
+	}{
+		{
+			name:   "window ended",
+			window: "2020-06-01",
+			want:   1,
+		},
+		{
+			name:   "current window",
+			window: digestWindowID(digestWindowDaily, time.Now()),
+			want:   0,
+		},
+		{
+			name: "nothing buffered",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := store.New("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.window != "" {
+				s.Put(digestBucket("b"), "a", store.Record{
+					Data: map[string]string{
+						recordKeyWindow: tt.window,
+						recordKeyData:   `{"item":{"title":"a"}}`,
+					},
+				})
+			}
+			d := newDeliveryTracker(context.Background(), s, nil, newReport(cnf, discardLogger{}), nil, nil)
+			tasks := deliverFetchResult(cnf, "b", FetchResult{NotModified: true}, d)
+			if len(tasks) != tt.want {
+				t.Errorf("deliverFetchResult() returned %d tasks, want %d", len(tasks), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/olegsu/rss-sync/pkg/template"
+)
+
+const (
+	directoryFormatJSON = "json"
+	directoryFormatRSS  = "rss"
+)
+
+type (
+	directorySource struct {
+		src Source
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindDirectory, func(src Source) (SourceKind, error) {
+		if src.Directory == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no directory config", src.Name)
+		}
+		return &directorySource{src: src}, nil
+	})
+}
+
+// Fetch reads the files of the directory that match the pattern, sorted by name
+// the items have "file" data with the name and the path of the file they were read from
+// in addition to "content" of json files or "item" and "feed" of rss files
+func (s *directorySource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	dir := template.String(&s.src.Directory.Path, nil)
+	pattern := s.src.Directory.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	names := []string{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		ok, err := filepath.Match(pattern, f.Name())
+		if err != nil {
+			return FetchResult{}, fmt.Errorf("Invalid pattern %s: %w", pattern, err)
+		}
+		if ok {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	result := FetchResult{}
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		path := filepath.Join(dir, name)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return result, err
+		}
+		file := map[string]interface{}{
+			"file": map[string]interface{}{
+				"name": name,
+				"path": path,
+			},
+		}
+		if s.src.Directory.Format == directoryFormatRSS {
+			items, err := feedItems(string(b), file)
+			if err != nil {
+				return result, fmt.Errorf("Failed to read %s: %w", path, err)
+			}
+			result.Items = append(result.Items, items...)
+			continue
+		}
+		items, err := jsonItems(string(b), jsonFormat{
+			typ: s.src.Directory.Type,
+		}, file)
+		if err != nil {
+			return result, fmt.Errorf("Failed to read %s: %w", path, err)
+		}
+		result.Items = append(result.Items, items...)
+	}
+	return result, nil
+}
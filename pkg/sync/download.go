@@ -0,0 +1,401 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	gosync "sync"
+)
+
+const (
+	targetKindDownload = "download"
+
+	// downloadURL is the url of the first enclosure of the item by default
+	downloadURL = `{{ with .item.enclosures }}{{ (index . 0).url }}{{ end }}`
+
+	// downloadPartSuffix is added to the name of the file while it is downloaded
+	downloadPartSuffix = ".part"
+	// downloadSidecarSuffix is added to the name of the file the metadata is written to
+	downloadSidecarSuffix = ".json"
+
+	// keys of the data stored for each downloaded item
+	storeKeyPath    = "path"
+	storeKeySize    = "size"
+	storeKeySkipped = "skipped"
+)
+
+type (
+	downloadTarget struct {
+		target Target
+	}
+
+	// downloadFile is the rendered download of one item
+	downloadFile struct {
+		URL     string `json:"url"`
+		Path    string `json:"path"`
+		Sidecar string `json:"sidecar,omitempty"`
+	}
+)
+
+var (
+	downloadSlotsMux gosync.Mutex
+	// downloadSlots limits the concurrent downloads of each target
+	downloadSlots = map[string]chan struct{}{}
+
+	sizeRegexp            = regexp.MustCompile(`^(\d+)\s*([KMGT]?)I?B?$`)
+	invalidFilenameRegexp = regexp.MustCompile(`[/\\:*?"<>|\x00-\x1f]`)
+)
+
+func init() {
+	RegisterTarget(targetKindDownload, func(target Target) (TargetKind, error) {
+		if target.Download == nil {
+			return nil, fmt.Errorf("Target \"%s\" has no download config", target.Name)
+		}
+		if target.Download.Directory == "" {
+			return nil, fmt.Errorf("Download target \"%s\" must have directory", target.Name)
+		}
+		if _, err := parseSize(target.Download.MaxSize); err != nil {
+			return nil, fmt.Errorf("Download target \"%s\" has invalid max-size: %w", target.Name, err)
+		}
+		return &downloadTarget{target: target}, nil
+	})
+}
+
+// parseSize parses size in bytes with optional unit, e.g. 500MB or 2G, units are 1024 based
+func parseSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	m := sizeRegexp.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(size)))
+	if m == nil {
+		return 0, fmt.Errorf("Invalid size \"%s\", e.g. 500MB", size)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if m[2] != "" {
+		for i := 0; i <= strings.Index("KMGT", m[2]); i++ {
+			n *= 1024
+		}
+	}
+	return n, nil
+}
+
+// Plan skips items that were already downloaded, items without enclosure
+// and items the feed says are larger than max-size
+func (t *downloadTarget) Plan(req DeliveryRequest) (Planned, error) {
+	if req.Previous != nil {
+		return Planned{}, nil
+	}
+	f, err := t.render(req.Data)
+	if err != nil {
+		return Planned{}, err
+	}
+	if f.URL == "" {
+		return Planned{}, nil
+	}
+	max, _ := parseSize(t.target.Download.MaxSize)
+	if size := attachmentInt(req.Data, f.URL, "size_in_bytes"); max > 0 && size > max {
+		return Planned{}, nil
+	}
+	return Planned{
+		Action:      actionCreate,
+		Title:       f.Path,
+		Description: f.URL,
+		Output:      f,
+	}, nil
+}
+
+// Deliver downloads the file next to a .part file that is resumed by the next run when the download fails
+// files larger than max-size are not downloaded and are recorded as skipped so they are not tried again
+func (t *downloadTarget) Deliver(ctx context.Context, req DeliveryRequest) (map[string]string, error) {
+	f, err := t.render(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	release, err := t.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return nil, err
+	}
+	size := int64(0)
+	if info, err := os.Stat(f.Path); err == nil {
+		// downloaded before the state was lost, unless the sidecar says it was downloaded from other url
+		if u := sidecarURL(f.Path + downloadSidecarSuffix); u != "" && u != f.URL {
+			return nil, fmt.Errorf("File %s was downloaded from %s", f.Path, u)
+		}
+		size = info.Size()
+	} else {
+		max, _ := parseSize(t.target.Download.MaxSize)
+		n, skipped, err := download(ctx, req.HTTPClient, f.URL, f.Path+downloadPartSuffix, max)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to download %s: %w", f.URL, err)
+		}
+		if skipped != "" {
+			return map[string]string{
+				storeKeySkipped: skipped,
+			}, nil
+		}
+		if err := os.Rename(f.Path+downloadPartSuffix, f.Path); err != nil {
+			return nil, err
+		}
+		size = n
+	}
+	if f.Sidecar != "" {
+		b, err := json.MarshalIndent(downloadMetadata(req.Data, f, size), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(f.Sidecar, b, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]string{
+		storeKeyPath: f.Path,
+		storeKeySize: strconv.FormatInt(size, 10),
+	}, nil
+}
+
+// acquire waits for one of the download slots of the target, 1 by default
+func (t *downloadTarget) acquire(ctx context.Context) (func(), error) {
+	n := t.target.Download.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	downloadSlotsMux.Lock()
+	key := fmt.Sprintf("%s%s%d", t.target.Name, seperator, n)
+	slots, ok := downloadSlots[key]
+	if !ok {
+		slots = make(chan struct{}, n)
+		downloadSlots[key] = slots
+	}
+	downloadSlotsMux.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// download writes the body of the url to the file, appending to what was downloaded to it before
+// when the server does not support ranges the file is downloaded from the start
+// it returns the reason the file was not downloaded when it is larger than max
+func download(ctx context.Context, client *http.Client, u string, name string, max int64) (int64, string, error) {
+	offset := int64(0)
+	if info, err := os.Stat(name); err == nil {
+		offset = info.Size()
+	}
+	r, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	if offset > 0 {
+		r.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// downloads take longer than the timeout of the requests the sources send, they are bound by the context
+	c := http.Client{}
+	if client != nil {
+		c = *client
+	}
+	c.Timeout = 0
+	resp, err := c.Do(r)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)):
+	case resp.StatusCode == http.StatusPartialContent:
+		// part of the file other than the requested one, the next run starts over
+		os.Remove(name)
+		return 0, "", fmt.Errorf("Request returned range \"%s\", expected range from %d", resp.Header.Get("Content-Range"), offset)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the file changed or was downloaded completely, the next run starts over
+		os.Remove(name)
+		return 0, "", fmt.Errorf("Request returned status %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return 0, "", fmt.Errorf("Request returned status %d", resp.StatusCode)
+	default:
+		offset = 0
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	if max > 0 && resp.ContentLength >= 0 && offset+resp.ContentLength > max {
+		os.Remove(name)
+		return 0, fmt.Sprintf("size %d is larger than max-size %d", offset+resp.ContentLength, max), nil
+	}
+	file, err := os.OpenFile(name, flags, 0644)
+	if err != nil {
+		return 0, "", err
+	}
+	body := io.Reader(resp.Body)
+	if max > 0 {
+		body = io.LimitReader(resp.Body, max-offset+1)
+	}
+	n, err := io.Copy(file, body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if max > 0 && offset+n > max {
+		os.Remove(name)
+		return 0, fmt.Sprintf("size is larger than max-size %d", max), nil
+	}
+	return offset + n, "", nil
+}
+
+// render returns the download of the item, no url when the item has nothing to download
+func (t *downloadTarget) render(data interface{}) (downloadFile, error) {
+	d := t.target.Download
+	u := d.URL
+	if u == "" {
+		u = downloadURL
+	}
+	f := downloadFile{}
+	u, err := renderField("url", u, data)
+	if err != nil {
+		return f, err
+	}
+	f.URL = strings.TrimSpace(u)
+	if f.URL == "" {
+		return f, nil
+	}
+	name := path.Base(f.URL)
+	if parsed, err := url.Parse(f.URL); err == nil {
+		name = path.Base(parsed.Path)
+	}
+	ext := path.Ext(name)
+	if d.Filename == "" {
+		// names like audio.mp3 are used by all the episodes of some feeds, the hash of the url tells them apart
+		h := sha1.Sum([]byte(f.URL))
+		name = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), hex.EncodeToString(h[:4]), ext)
+	} else {
+		if name, err = renderField("filename", d.Filename, data); err != nil {
+			return f, err
+		}
+		name = strings.TrimSpace(name)
+		// titles like "Ep. 1" have an extension of their own
+		if !strings.HasSuffix(strings.ToLower(name), strings.ToLower(ext)) {
+			name += ext
+		}
+	}
+	dir, err := renderField("directory", d.Directory, data)
+	if err != nil {
+		return f, err
+	}
+	f.Path = filepath.Join(dir, sanitizeFilename(name))
+	if d.Sidecar {
+		f.Sidecar = f.Path + downloadSidecarSuffix
+	}
+	return f, nil
+}
+
+// sidecarURL returns the url of the file the sidecar was written for, empty when there is no sidecar
+func sidecarURL(name string) string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	meta := struct {
+		URL string `json:"url"`
+	}{}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return ""
+	}
+	return meta.URL
+}
+
+// sanitizeFilename replaces the characters that are not allowed in file names, e.g. the slash of the title of the item
+func sanitizeFilename(name string) string {
+	name = strings.Trim(invalidFilenameRegexp.ReplaceAllString(name, "_"), " .")
+	if name == "" {
+		return "download"
+	}
+	return name
+}
+
+// attachmentInt returns the field of the attachment of the item with the url, e.g. the size as written in the feed
+// 0 when it is not known
+func attachmentInt(data interface{}, u string, field string) int64 {
+	item, _ := itemData(data)
+	attachments, _ := item["attachments"].([]interface{})
+	for _, a := range attachments {
+		attachment, _ := a.(map[string]interface{})
+		if attachment["url"] != u {
+			continue
+		}
+		switch n := attachment[field].(type) {
+		case int64:
+			return n
+		case int:
+			return int64(n)
+		case float64:
+			return int64(n)
+		}
+	}
+	return 0
+}
+
+// downloadMetadata returns the metadata written to the sidecar file, named after the ID3 tags the players show
+func downloadMetadata(data interface{}, f downloadFile, size int64) map[string]interface{} {
+	item, feed := itemData(data)
+	str := func(m map[string]interface{}, keys ...string) string {
+		for _, k := range keys {
+			if m == nil {
+				return ""
+			}
+			if s, ok := m[k].(string); ok {
+				return s
+			}
+			m, _ = m[k].(map[string]interface{})
+		}
+		return ""
+	}
+	firstOf := func(values ...string) string {
+		for _, v := range values {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	meta := map[string]interface{}{
+		"title":       str(item, "title"),
+		"artist":      firstOf(str(item, "itunes", "author"), str(item, "author", "name"), str(feed, "itunes", "author"), str(feed, "title")),
+		"album":       str(feed, "title"),
+		"track":       str(item, "itunes", "episode"),
+		"season":      str(item, "itunes", "season"),
+		"date":        firstOf(str(item, "publishedParsed"), str(item, "published")),
+		"description": firstOf(str(item, "itunes", "summary"), str(item, "description")),
+		"duration":    attachmentInt(data, f.URL, "duration_in_seconds"),
+		"image":       firstOf(str(item, "media", "thumbnail"), str(feed, "itunes", "image")),
+		"link":        str(item, "link"),
+		"guid":        str(item, "guid"),
+		"url":         f.URL,
+		"file":        filepath.Base(f.Path),
+		"size":        size,
+	}
+	if date, ok := meta["date"].(string); ok && len(date) >= 4 {
+		meta["year"] = date[:4]
+	}
+	return meta
+}
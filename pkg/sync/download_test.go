@@ -0,0 +1,366 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/olegsu/rss-sync/pkg/values"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDownload(t *testing.T) {
+	const content = "0123456789"
+	tests := []struct {
+		name string
+		// part is what was downloaded to the .part file before
+		part    string
+		max     int64
+		handler http.HandlerFunc
+		want    string
+		skipped bool
+		wantErr bool
+		// removed is set when the .part file is removed
+		removed bool
+	}{
+		{
+			name: "download",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			},
+			want: content,
+		},
+		{
+			name: "resume",
+			part: "0123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "bytes=4-" {
+					t.Errorf("Range = %s", r.Header.Get("Range"))
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 4-9/%d", len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[4:]))
+			},
+			want: content,
+		},
+		{
+			name: "server without ranges starts over",
+			part: "xxxx",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			},
+			want: content,
+		},
+		{
+			name: "range other than the requested one",
+			part: "0123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 2-9/%d", len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[2:]))
+			},
+			wantErr: true,
+			removed: true,
+		},
+		{
+			name: "partial content without content-range",
+			part: "0123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[4:]))
+			},
+			wantErr: true,
+			removed: true,
+		},
+		{
+			name: "range not satisfiable",
+			part: content,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			},
+			wantErr: true,
+			removed: true,
+		},
+		{
+			name: "error status keeps the part",
+			part: "0123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantErr: true,
+			want:    "0123",
+		},
+		{
+			name: "larger than max",
+			max:  5,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			},
+			skipped: true,
+			removed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			dir, err := ioutil.TempDir("", "download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			name := filepath.Join(dir, "file"+downloadPartSuffix)
+			if tt.part != "" {
+				if err := ioutil.WriteFile(name, []byte(tt.part), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			n, skipped, err := download(context.Background(), srv.Client(), srv.URL, name, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (skipped != "") != tt.skipped {
+				t.Errorf("download() skipped = %q, want skipped %v", skipped, tt.skipped)
+			}
+			b, err := ioutil.ReadFile(name)
+			if tt.removed {
+				if !os.IsNotExist(err) {
+					t.Errorf("download() kept %s", string(b))
+				}
+				return
+			}
+			if string(b) != tt.want {
+				t.Errorf("download() wrote %s, want %s", string(b), tt.want)
+			}
+			if !tt.wantErr && n != int64(len(tt.want)) {
+				t.Errorf("download() = %d, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+// downloadTargetFromYAML returns the download target of the config
+func downloadTargetFromYAML(t *testing.T, config string) *downloadTarget {
+	target := Target{}
+	if err := yaml.Unmarshal([]byte(config), &target); err != nil {
+		t.Fatal(err)
+	}
+	kind, err := buildTargetKind(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kind.(*downloadTarget)
+}
+
+// downloadData returns the data of item with enclosure
+func downloadData(title string, u string) *values.Values {
+	item := map[string]interface{}{
+		"title": title,
+		"itunes": map[string]interface{}{
+			"episode": "7",
+		},
+	}
+	if u != "" {
+		item["enclosures"] = []interface{}{
+			map[string]interface{}{"url": u},
+		}
+	}
+	return &values.Values{
+		"item": item,
+		"feed": map[string]interface{}{
+			"title": "Pod",
+		},
+	}
+}
+
+func TestDownloadRender(t *testing.T) {
+	hash := func(u string) string {
+		h := sha1.Sum([]byte(u))
+		return hex.EncodeToString(h[:4])
+	}
+	tests := []struct {
+		name   string
+		config string
+		data   *values.Values
+		want   downloadFile
+	}{
+		{
+			name:   "name of the file in the url with hash of the url",
+			config: "name: d\ndownload:\n  directory: /media/{{ .feed.title }}",
+			data:   downloadData("Ep 1", "http://cdn/ep1/audio.mp3"),
+			want: downloadFile{
+				URL:  "http://cdn/ep1/audio.mp3",
+				Path: filepath.Join("/media/Pod", "audio-"+hash("http://cdn/ep1/audio.mp3")+".mp3"),
+			},
+		},
+		{
+			name:   "query of the url",
+			config: "name: d\ndownload:\n  directory: /media",
+			data:   downloadData("Ep 1", "http://cdn/media.mp3?id=1"),
+			want: downloadFile{
+				URL:  "http://cdn/media.mp3?id=1",
+				Path: filepath.Join("/media", "media-"+hash("http://cdn/media.mp3?id=1")+".mp3"),
+			},
+		},
+		{
+			name:   "filename with the extension of the url",
+			config: "name: d\ndownload:\n  directory: /media\n  filename: '{{ .item.itunes.episode }} - {{ .item.title }}'",
+			data:   downloadData("Ep 1/2", "http://cdn/audio.mp3"),
+			want: downloadFile{
+				URL:  "http://cdn/audio.mp3",
+				Path: filepath.Join("/media", "7 - Ep 1_2.mp3"),
+			},
+		},
+		{
+			name:   "filename that ends with the extension",
+			config: "name: d\ndownload:\n  directory: /media\n  filename: '{{ .item.title }}'",
+			data:   downloadData("Ep. 1.MP3", "http://cdn/audio.mp3"),
+			want: downloadFile{
+				URL:  "http://cdn/audio.mp3",
+				Path: filepath.Join("/media", "Ep. 1.MP3"),
+			},
+		},
+		{
+			name:   "sidecar",
+			config: "name: d\ndownload:\n  directory: /media\n  filename: '{{ .item.title }}'\n  sidecar: true",
+			data:   downloadData("Ep 1", "http://cdn/audio.mp3"),
+			want: downloadFile{
+				URL:     "http://cdn/audio.mp3",
+				Path:    filepath.Join("/media", "Ep 1.mp3"),
+				Sidecar: filepath.Join("/media", "Ep 1.mp3.json"),
+			},
+		},
+		{
+			name:   "item without enclosure",
+			config: "name: d\ndownload:\n  directory: /media",
+			data:   downloadData("Ep 1", ""),
+			want:   downloadFile{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := downloadTargetFromYAML(t, tt.config).render(tt.data)
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadDeliverExistingFile(t *testing.T) {
+	const u = "http://cdn/audio.mp3"
+	tests := []struct {
+		name string
+		// sidecar is the url written to the sidecar of the file, no sidecar when empty
+		sidecar string
+		wantErr bool
+	}{
+		{
+			name: "file without sidecar",
+		},
+		{
+			name:    "file with sidecar of the url",
+			sidecar: u,
+		},
+		{
+			name:    "file with sidecar of other url",
+			sidecar: "http://cdn/other/audio.mp3",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			target := downloadTargetFromYAML(t, fmt.Sprintf("name: d\ndownload:\n  directory: %s\n  filename: episode", dir))
+			name := filepath.Join(dir, "episode.mp3")
+			if err := ioutil.WriteFile(name, []byte("0123"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sidecar != "" {
+				if err := ioutil.WriteFile(name+downloadSidecarSuffix, []byte(fmt.Sprintf(`{"url": %q}`, tt.sidecar)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// the url is not requested, the file exists
+			got, err := target.Deliver(context.Background(), DeliveryRequest{Data: downloadData("Ep 1", u)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := map[string]string{
+				storeKeyPath: name,
+				storeKeySize: "4",
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Deliver() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDownloadDeliverSidecar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := downloadTargetFromYAML(t, fmt.Sprintf("name: d\ndownload:\n  directory: %s\n  filename: '{{ .item.title }}'\n  sidecar: true", dir))
+	u := srv.URL + "/audio.mp3"
+	got, err := target.Deliver(context.Background(), DeliveryRequest{
+		HTTPClient: srv.Client(),
+		Data:       downloadData("Ep 1", u),
+	})
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	name := filepath.Join(dir, "Ep 1.mp3")
+	if got[storeKeyPath] != name || got[storeKeySize] != "10" {
+		t.Errorf("Deliver() = %v", got)
+	}
+	if _, err := os.Stat(name + downloadPartSuffix); !os.IsNotExist(err) {
+		t.Errorf("part file was not renamed")
+	}
+	b, err := ioutil.ReadFile(name + downloadSidecarSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := map[string]interface{}{}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"title": "Ep 1",
+		"album": "Pod",
+		"track": "7",
+		"url":   u,
+		"file":  "Ep 1.mp3",
+		"size":  float64(10),
+	}
+	for k, v := range want {
+		if meta[k] != v {
+			t.Errorf("sidecar %s = %v, want %v", k, meta[k], v)
+		}
+	}
+}
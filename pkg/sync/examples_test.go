@@ -0,0 +1,181 @@
+package sync
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/expression"
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/olegsu/rss-sync/pkg/values"
+)
+
+var (
+	// readmeFilterRegexp matches the entries of the filter blocks of README, e.g. about-go: 'item.title contains "Go"'
+	readmeFilterRegexp = regexp.MustCompile(`^\s*([\w-]+): '(.*)'$`)
+)
+
+// exampleFilterData returns item of each kind of source that passes the filters of README and example
+func exampleFilterData() *values.Values {
+	now := time.Now()
+	return &values.Values{
+		"item": map[string]interface{}{
+			"title":           "Episode 12: Go",
+			"content":         "content",
+			"publishedParsed": now.Add(-time.Hour).Format(time.RFC3339),
+		},
+		"feed": map[string]interface{}{
+			"title": "feed",
+		},
+		"event": map[string]interface{}{
+			"summary": "meeting",
+			"status":  "confirmed",
+		},
+		"issue": map[string]interface{}{
+			"key": "KEY-1",
+		},
+		"content": map[string]interface{}{
+			"days": []interface{}{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+		},
+		"source":  map[string]interface{}{"name": "source"},
+		"binding": map[string]interface{}{"name": "binding"},
+		"target":  map[string]interface{}{"name": "target"},
+	}
+}
+
+// readmeFilters returns the filters of the yaml blocks of README by name, commented examples included
+func readmeFilters(t *testing.T) map[string]string {
+	b, err := ioutil.ReadFile(filepath.Join("..", "..", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := map[string]string{}
+	indent := -1
+	// commented is set for filter blocks that are commented out, their entries are commented as well
+	commented := false
+	for _, line := range strings.Split(string(b), "\n") {
+		comment := strings.HasPrefix(strings.TrimSpace(line), "#")
+		if comment && (commented || indent < 0) {
+			line = strings.Replace(line, "#", " ", 1)
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		current := len(line) - len(strings.TrimLeft(line, " "))
+		if trimmed == "filter:" {
+			indent = current
+			commented = comment
+			continue
+		}
+		if indent < 0 {
+			continue
+		}
+		if current <= indent || strings.HasPrefix(trimmed, "```") {
+			indent = -1
+			continue
+		}
+		if m := readmeFilterRegexp.FindStringSubmatch(line); m != nil {
+			// the values are in single quotes, the quote is escaped by doubling it
+			filters[m[1]] = strings.ReplaceAll(m[2], "''", "'")
+		}
+	}
+	return filters
+}
+
+func TestReadmeAndExampleFilters(t *testing.T) {
+	filters := map[string]string{}
+	for name, f := range readmeFilters(t) {
+		filters["README.md "+name] = f
+	}
+	if len(filters) == 0 {
+		t.Fatal("no filters found in README.md")
+	}
+	files, err := filepath.Glob(filepath.Join("..", "..", "example", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		for _, err := range ValidateFile(file) {
+			t.Errorf("%v", err)
+		}
+		cnf, err := Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, src := range cnf.Sources {
+			for name, f := range src.Filter {
+				filters[filepath.Base(file)+" "+src.Name+" "+name] = f
+			}
+		}
+		for _, binding := range cnf.Bindings {
+			for _, target := range binding.Targets {
+				for name, f := range target.Filter {
+					filters[filepath.Base(file)+" "+binding.Name+" "+name] = f
+				}
+			}
+		}
+	}
+	for name, f := range filters {
+		t.Run(name, func(t *testing.T) {
+			validate := func(f string) error {
+				return expression.Validate(f, filterVariables)
+			}
+			if expression.IsTemplate(f) {
+				validate = template.Validate
+			}
+			if err := validate(f); err != nil {
+				t.Fatalf("filter %s does not compile: %v", f, err)
+			}
+			passed, err := filter(exampleFilterData(), f)
+			if err != nil {
+				t.Fatalf("filter %s failed: %v", f, err)
+			}
+			if !passed {
+				t.Errorf("filter %s did not pass the example item", f)
+			}
+		})
+	}
+}
+
+func TestJustReleasedFilter(t *testing.T) {
+	f := readmeFilters(t)["just-released"]
+	if f == "" {
+		t.Fatal("just-released filter not found in README.md")
+	}
+	tests := []struct {
+		name      string
+		published time.Duration
+		want      bool
+	}{
+		{
+			name:      "released an hour ago",
+			published: time.Hour,
+			want:      true,
+		},
+		{
+			name:      "released two days ago",
+			published: 48 * time.Hour,
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &values.Values{
+				"item": map[string]interface{}{
+					"publishedParsed": time.Now().Add(-tt.published).Format(time.RFC3339),
+				},
+			}
+			got, err := filter(data, f)
+			if err != nil {
+				t.Fatalf("filter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+)
+
+const (
+	targetKindFeed = "feed"
+
+	feedFormatRSS  = "rss"
+	feedFormatAtom = "atom"
+
+	// feedMaxItems is the number of items the feed keeps by default
+	feedMaxItems = 50
+	// feedStateSuffix is added to the path of the feed for the state file by default
+	feedStateSuffix = ".state.json"
+
+	feedItemTitle       = `{{ .item.title }}`
+	feedItemLink        = `{{ .item.link }}`
+	feedItemDescription = `{{ .item.description }}`
+	feedItemPublished   = `{{ .item.publishedParsed }}`
+)
+
+type (
+	feedTarget struct {
+		target Target
+	}
+
+	// feedEntry is one item of the feed as kept in the state file, newest first
+	feedEntry struct {
+		ID          string         `json:"id"`
+		Title       string         `json:"title"`
+		Link        string         `json:"link,omitempty"`
+		Description string         `json:"description,omitempty"`
+		Published   time.Time      `json:"published"`
+		Enclosure   *feedEnclosure `json:"enclosure,omitempty"`
+	}
+
+	feedEnclosure struct {
+		URL    string `json:"url" xml:"url,attr"`
+		Length string `json:"length,omitempty" xml:"length,attr"`
+		Type   string `json:"type,omitempty" xml:"type,attr"`
+	}
+
+	rssDocument struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		Title         string    `xml:"title"`
+		Link          string    `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate"`
+		Items         []rssItem `xml:"item"`
+	}
+
+	rssItem struct {
+		Title       string         `xml:"title"`
+		Link        string         `xml:"link,omitempty"`
+		Description string         `xml:"description,omitempty"`
+		GUID        rssGUID        `xml:"guid"`
+		PubDate     string         `xml:"pubDate"`
+		Enclosure   *feedEnclosure `xml:"enclosure,omitempty"`
+	}
+
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+
+	atomDocument struct {
+		XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		Title    string      `xml:"title"`
+		Subtitle string      `xml:"subtitle,omitempty"`
+		ID       string      `xml:"id"`
+		Updated  string      `xml:"updated"`
+		Author   atomAuthor  `xml:"author"`
+		Links    []atomLink  `xml:"link"`
+		Entries  []atomEntry `xml:"entry"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomLink struct {
+		Href   string `xml:"href,attr"`
+		Rel    string `xml:"rel,attr,omitempty"`
+		Type   string `xml:"type,attr,omitempty"`
+		Length string `xml:"length,attr,omitempty"`
+	}
+
+	atomEntry struct {
+		Title     string     `xml:"title"`
+		ID        string     `xml:"id"`
+		Updated   string     `xml:"updated"`
+		Published string     `xml:"published"`
+		Links     []atomLink `xml:"link"`
+		Summary   *atomText  `xml:"summary,omitempty"`
+	}
+
+	atomText struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+)
+
+var (
+	// feedMux serializes the updates of the feeds, the items of one run are delivered concurrently
+	feedMux gosync.Mutex
+)
+
+func init() {
+	RegisterTarget(targetKindFeed, func(target Target) (TargetKind, error) {
+		if target.Feed == nil {
+			return nil, fmt.Errorf("Target \"%s\" has no feed config", target.Name)
+		}
+		if target.Feed.Path == "" {
+			return nil, fmt.Errorf("Feed target \"%s\" must have path", target.Name)
+		}
+		return &feedTarget{target: target}, nil
+	})
+}
+
+// Plan skips items that were already added to the feed
+func (t *feedTarget) Plan(req DeliveryRequest) (Planned, error) {
+	if req.Previous != nil {
+		return Planned{}, nil
+	}
+	e, err := t.render(req)
+	if err != nil {
+		return Planned{}, err
+	}
+	return Planned{
+		Action:      actionCreate,
+		Title:       e.Title,
+		Description: e.Link,
+		Output:      e,
+	}, nil
+}
+
+// Deliver adds the item to the state file and writes the feed with the latest published items of the state
+func (t *feedTarget) Deliver(ctx context.Context, req DeliveryRequest) (map[string]string, error) {
+	e, err := t.render(req)
+	if err != nil {
+		return nil, err
+	}
+	feedMux.Lock()
+	defer feedMux.Unlock()
+	path, state, err := t.paths()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readFeedState(state)
+	if err != nil {
+		return nil, err
+	}
+	updated := addFeedEntry(entries, e, t.target.Feed.MaxItems)
+	b, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(state, b); err != nil {
+		return nil, err
+	}
+	doc, err := t.document(updated)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, doc); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// addFeedEntry returns the entries with the entry, newest first, trimmed to max entries
+// the entry replaces the entry with the same id, feedMaxItems are kept when max is not set
+func addFeedEntry(entries []feedEntry, e feedEntry, max int) []feedEntry {
+	updated := []feedEntry{e}
+	for _, existing := range entries {
+		if existing.ID != e.ID {
+			updated = append(updated, existing)
+		}
+	}
+	// items of one run are delivered in any order
+	sort.SliceStable(updated, func(i, j int) bool {
+		return updated[i].Published.After(updated[j].Published)
+	})
+	if max <= 0 {
+		max = feedMaxItems
+	}
+	if len(updated) > max {
+		updated = updated[:max]
+	}
+	return updated
+}
+
+// paths returns the path of the feed and of its state file
+func (t *feedTarget) paths() (string, string, error) {
+	f := t.target.Feed
+	path, err := renderField("path", f.Path, nil)
+	if err != nil {
+		return "", "", err
+	}
+	state := path + feedStateSuffix
+	if f.State != "" {
+		if state, err = renderField("state", f.State, nil); err != nil {
+			return "", "", err
+		}
+	}
+	return path, state, nil
+}
+
+// render returns the entry of the item, the published time of the item or now when it is unknown
+func (t *feedTarget) render(req DeliveryRequest) (feedEntry, error) {
+	f := t.target.Feed
+	var err error
+	tmpl := func(field string, t string, def string) string {
+		if err != nil {
+			return ""
+		}
+		if t == "" {
+			t = def
+		}
+		res, rerr := renderField(field, t, req.Data)
+		if rerr != nil {
+			// the first error is returned
+			err = rerr
+			return ""
+		}
+		res = strings.TrimSpace(res)
+		// fields the item does not have render as "<no value>"
+		if res == "<no value>" {
+			return ""
+		}
+		return res
+	}
+	e := feedEntry{
+		ID:          req.Key,
+		Title:       tmpl("item.title", f.Item.Title, feedItemTitle),
+		Link:        tmpl("item.link", f.Item.Link, feedItemLink),
+		Description: tmpl("item.description", f.Item.Description, feedItemDescription),
+		Published:   time.Now().UTC(),
+	}
+	published := tmpl("item.published", f.Item.Published, feedItemPublished)
+	if err != nil {
+		return e, err
+	}
+	if p, err := time.Parse(time.RFC3339, published); err == nil {
+		e.Published = p
+	}
+	if e.ID == "" {
+		e.ID = e.Link
+	}
+	item, _ := itemData(req.Data)
+	if enclosures, ok := item["enclosures"].([]interface{}); ok && len(enclosures) > 0 {
+		if enclosure, ok := enclosures[0].(map[string]interface{}); ok {
+			e.Enclosure = &feedEnclosure{}
+			e.Enclosure.URL, _ = enclosure["url"].(string)
+			e.Enclosure.Length, _ = enclosure["length"].(string)
+			e.Enclosure.Type, _ = enclosure["type"].(string)
+			// the length is required by rss, 0 when it is unknown
+			if e.Enclosure.Length == "" {
+				e.Enclosure.Length = "0"
+			}
+		}
+	}
+	// entries without id would replace each other, they are identified by a hash of their content
+	if e.ID == "" {
+		e.ID = feedEntryKey(e)
+	}
+	return e, nil
+}
+
+// feedEntryKey returns hash of the title, the description and the enclosure of the entry
+func feedEntryKey(e feedEntry) string {
+	content := []string{e.Title, e.Description}
+	if e.Enclosure != nil {
+		content = append(content, e.Enclosure.URL)
+	}
+	h := sha1.Sum([]byte(strings.Join(content, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// document returns the feed of the entries in the format of the target
+func (t *feedTarget) document(entries []feedEntry) ([]byte, error) {
+	f := t.target.Feed
+	title, err := renderField("title", f.Title, nil)
+	if err != nil {
+		return nil, err
+	}
+	link, err := renderField("link", f.Link, nil)
+	if err != nil {
+		return nil, err
+	}
+	description, err := renderField("description", f.Description, nil)
+	if err != nil {
+		return nil, err
+	}
+	updated := time.Now().UTC()
+	if len(entries) > 0 {
+		updated = entries[0].Published
+	}
+	var doc interface{}
+	if f.Format == feedFormatAtom {
+		atom := atomDocument{
+			Title:    title,
+			Subtitle: description,
+			ID:       link,
+			Updated:  updated.Format(time.RFC3339),
+			Author: atomAuthor{
+				Name: title,
+			},
+		}
+		if link != "" {
+			atom.Links = append(atom.Links, atomLink{Href: link, Rel: "self"})
+		}
+		if atom.ID == "" {
+			atom.ID = feedURN(title)
+		}
+		for _, e := range entries {
+			entry := atomEntry{
+				Title:     e.Title,
+				ID:        feedURN(e.ID),
+				Updated:   e.Published.Format(time.RFC3339),
+				Published: e.Published.Format(time.RFC3339),
+			}
+			if e.Link != "" {
+				entry.Links = append(entry.Links, atomLink{Href: e.Link, Rel: "alternate"})
+			}
+			if e.Enclosure != nil {
+				entry.Links = append(entry.Links, atomLink{Href: e.Enclosure.URL, Rel: "enclosure", Type: e.Enclosure.Type, Length: e.Enclosure.Length})
+			}
+			if e.Description != "" {
+				entry.Summary = &atomText{Type: "html", Body: e.Description}
+			}
+			atom.Entries = append(atom.Entries, entry)
+		}
+		doc = atom
+	} else {
+		rss := rssDocument{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:         title,
+				Link:          link,
+				Description:   description,
+				LastBuildDate: updated.Format(time.RFC1123Z),
+			},
+		}
+		for _, e := range entries {
+			rss.Channel.Items = append(rss.Channel.Items, rssItem{
+				Title:       e.Title,
+				Link:        e.Link,
+				Description: e.Description,
+				GUID: rssGUID{
+					IsPermaLink: e.ID == e.Link && e.Link != "",
+					Value:       e.ID,
+				},
+				PubDate:   e.Published.Format(time.RFC1123Z),
+				Enclosure: e.Enclosure,
+			})
+		}
+		doc = rss
+	}
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build feed: %w", err)
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// feedURN returns the id as atom id, ids that are not urls are hashed
+func feedURN(id string) string {
+	if strings.HasPrefix(id, "http://") || strings.HasPrefix(id, "https://") || strings.HasPrefix(id, "urn:") || strings.HasPrefix(id, "tag:") {
+		return id
+	}
+	h := sha1.Sum([]byte(id))
+	return "urn:sha1:" + hex.EncodeToString(h[:])
+}
+
+// readFeedState returns the entries kept in the state file, none when it does not exist
+func readFeedState(name string) ([]feedEntry, error) {
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []feedEntry{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("Failed to read feed state %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// writeFileAtomic writes the file next to it and renames it, static file servers never serve half written feed
+func writeFileAtomic(name string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp := name + ".tmp" + strconv.Itoa(os.Getpid())
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,298 @@
+package sync
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/olegsu/rss-sync/pkg/values"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAddFeedEntry(t *testing.T) {
+	entry := func(id string, day int) feedEntry {
+		return feedEntry{
+			ID:        id,
+			Title:     id,
+			Published: time.Date(2020, 6, day, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	many := func(n int) []feedEntry {
+		res := []feedEntry{}
+		for i := n; i > 0; i-- {
+			res = append(res, entry(fmt.Sprintf("e%d", i), i%28+1))
+		}
+		return res
+	}
+	tests := []struct {
+		name    string
+		entries []feedEntry
+		entry   feedEntry
+		max     int
+		// ids of the entries that are kept
+		want    []string
+		wantLen int
+	}{
+		{
+			name:    "first entry",
+			entries: []feedEntry{},
+			entry:   entry("a", 1),
+			max:     10,
+			want:    []string{"a"},
+		},
+		{
+			name:    "newest first",
+			entries: []feedEntry{entry("c", 3), entry("a", 1)},
+			entry:   entry("b", 2),
+			max:     10,
+			want:    []string{"c", "b", "a"},
+		},
+		{
+			name:    "entry with the same id is replaced",
+			entries: []feedEntry{entry("b", 2), entry("a", 1)},
+			entry:   feedEntry{ID: "a", Title: "updated", Published: entry("a", 1).Published},
+			max:     10,
+			want:    []string{"b", "a"},
+		},
+		{
+			name:    "oldest entries are trimmed",
+			entries: []feedEntry{entry("c", 3), entry("b", 2), entry("a", 1)},
+			entry:   entry("d", 4),
+			max:     2,
+			want:    []string{"d", "c"},
+		},
+		{
+			name:    "entry older than the kept entries is trimmed",
+			entries: []feedEntry{entry("c", 3), entry("b", 2)},
+			entry:   entry("a", 1),
+			max:     2,
+			want:    []string{"c", "b"},
+		},
+		{
+			name:    "state with more entries than max is trimmed",
+			entries: []feedEntry{entry("d", 4), entry("c", 3), entry("b", 2)},
+			entry:   entry("a", 1),
+			max:     1,
+			want:    []string{"d"},
+		},
+		{
+			name:    "default max",
+			entries: many(feedMaxItems + 5),
+			entry:   entry("new", 28),
+			max:     0,
+			wantLen: feedMaxItems,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := addFeedEntry(tt.entries, tt.entry, tt.max)
+			if tt.want == nil {
+				if len(got) != tt.wantLen {
+					t.Errorf("addFeedEntry() kept %d entries, want %d", len(got), tt.wantLen)
+				}
+				return
+			}
+			ids := []string{}
+			for _, e := range got {
+				ids = append(ids, e.ID)
+				if e.ID == tt.entry.ID && e.Title != tt.entry.Title {
+					t.Errorf("addFeedEntry() kept the previous entry %s", e.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("addFeedEntry() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// feedTargetFromYAML returns the feed target of the config
+func feedTargetFromYAML(t *testing.T, config string) *feedTarget {
+	target := Target{}
+	if err := yaml.Unmarshal([]byte(config), &target); err != nil {
+		t.Fatal(err)
+	}
+	kind, err := buildTargetKind(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kind.(*feedTarget)
+}
+
+func TestFeedRenderID(t *testing.T) {
+	target := feedTargetFromYAML(t, "name: f\nfeed:\n  path: /tmp/feed.xml")
+	item := func(title string, link string) *values.Values {
+		i := map[string]interface{}{
+			"title": title,
+		}
+		if link != "" {
+			i["link"] = link
+		}
+		return &values.Values{"item": i}
+	}
+	tests := []struct {
+		name string
+		a    DeliveryRequest
+		b    DeliveryRequest
+		// same is set when both entries have the same id
+		same bool
+	}{
+		{
+			name: "key",
+			a:    DeliveryRequest{Key: "1", Data: item("a", "https://example.com/a")},
+			b:    DeliveryRequest{Key: "1", Data: item("b", "https://example.com/b")},
+			same: true,
+		},
+		{
+			name: "link without key",
+			a:    DeliveryRequest{Data: item("a", "https://example.com/a")},
+			b:    DeliveryRequest{Data: item("b", "https://example.com/a")},
+			same: true,
+		},
+		{
+			name: "hash of the content without key and link",
+			a:    DeliveryRequest{Data: item("a", "")},
+			b:    DeliveryRequest{Data: item("b", "")},
+			same: false,
+		},
+		{
+			name: "hash of the same content",
+			a:    DeliveryRequest{Data: item("a", "")},
+			b:    DeliveryRequest{Data: item("a", "")},
+			same: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := target.render(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := target.render(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.ID == "" || b.ID == "" {
+				t.Fatalf("render() id is empty")
+			}
+			if (a.ID == b.ID) != tt.same {
+				t.Errorf("render() ids = %s and %s, same %v", a.ID, b.ID, tt.same)
+			}
+		})
+	}
+}
+
+func TestFeedDocument(t *testing.T) {
+	entries := []feedEntry{
+		{
+			ID:          "https://example.com/2",
+			Title:       "Episode 2",
+			Link:        "https://example.com/2",
+			Description: "<p>second</p>",
+			Published:   time.Date(2020, 6, 2, 10, 0, 0, 0, time.UTC),
+			Enclosure: &feedEnclosure{
+				URL:    "https://cdn.example.com/2.mp3",
+				Length: "1024",
+				Type:   "audio/mpeg",
+			},
+		},
+		{
+			ID:        "guid-1",
+			Title:     "Episode 1",
+			Link:      "https://example.com/1",
+			Published: time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	tests := []struct {
+		name     string
+		format   string
+		wantType string
+		// wantIDs are the ids of the entries as the parser reads them
+		wantIDs []string
+		// wantContains are parts of the document
+		wantContains []string
+	}{
+		{
+			name:     "rss",
+			format:   "rss",
+			wantType: "rss",
+			wantIDs:  []string{"https://example.com/2", "guid-1"},
+			wantContains: []string{
+				`<guid isPermaLink="true">https://example.com/2</guid>`,
+				`<guid isPermaLink="false">guid-1</guid>`,
+				`<enclosure url="https://cdn.example.com/2.mp3" length="1024" type="audio/mpeg"></enclosure>`,
+				`<pubDate>Tue, 02 Jun 2020 10:00:00 +0000</pubDate>`,
+				`<lastBuildDate>Tue, 02 Jun 2020 10:00:00 +0000</lastBuildDate>`,
+			},
+		},
+		{
+			name:     "atom",
+			format:   "atom",
+			wantType: "atom",
+			wantIDs:  []string{"https://example.com/2", feedURN("guid-1")},
+			wantContains: []string{
+				`<updated>2020-06-02T10:00:00Z</updated>`,
+				`<link href="https://example.com/feed.xml" rel="self"></link>`,
+				`<link href="https://cdn.example.com/2.mp3" rel="enclosure" type="audio/mpeg" length="1024"></link>`,
+				`<summary type="html">&lt;p&gt;second&lt;/p&gt;</summary>`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := feedTargetFromYAML(t, fmt.Sprintf("name: f\nfeed:\n  path: /tmp/feed.xml\n  format: %s\n  title: Pod\n  link: https://example.com/feed.xml\n  description: Episodes", tt.format))
+			b, err := target.document(entries)
+			if err != nil {
+				t.Fatalf("document() error = %v", err)
+			}
+			for _, c := range tt.wantContains {
+				if !strings.Contains(string(b), c) {
+					t.Errorf("document() does not contain %s:\n%s", c, string(b))
+				}
+			}
+			feed, err := gofeed.NewParser().ParseString(string(b))
+			if err != nil {
+				t.Fatalf("document() is not a valid feed: %v", err)
+			}
+			if feed.FeedType != tt.wantType || feed.Title != "Pod" {
+				t.Errorf("document() = %s feed %s", feed.FeedType, feed.Title)
+			}
+			ids, titles := []string{}, []string{}
+			for _, item := range feed.Items {
+				ids = append(ids, item.GUID)
+				titles = append(titles, item.Title)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("document() ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(titles, []string{"Episode 2", "Episode 1"}) {
+				t.Errorf("document() titles = %v", titles)
+			}
+			if len(feed.Items[0].Enclosures) != 1 || feed.Items[0].Enclosures[0].URL != "https://cdn.example.com/2.mp3" {
+				t.Errorf("document() enclosures = %v", feed.Items[0].Enclosures)
+			}
+		})
+	}
+}
+
+func TestFeedDocumentWithoutEntries(t *testing.T) {
+	for _, format := range []string{"rss", "atom"} {
+		t.Run(format, func(t *testing.T) {
+			target := feedTargetFromYAML(t, "name: f\nfeed:\n  path: /tmp/feed.xml\n  title: Pod\n  format: "+format)
+			b, err := target.document(nil)
+			if err != nil {
+				t.Fatalf("document() error = %v", err)
+			}
+			feed, err := gofeed.NewParser().ParseString(string(b))
+			if err != nil {
+				t.Fatalf("document() is not a valid feed: %v", err)
+			}
+			if len(feed.Items) != 0 || feed.Title != "Pod" {
+				t.Errorf("document() = %+v", feed)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// feedItemToJSON returns the item with fields of the extensions the templates can use without going through "extensions"
+// itunes: podcast fields, e.g. .item.itunes.duration
+// media: media rss thumbnail and content, e.g. .item.media.thumbnail
+// dc: dublin core fields, e.g. .item.dc.creator
+// ext: text of elements of any other namespace by prefix and name, e.g. .item.ext.slash.comments
+// attachments: the attachments of json feed items or the enclosures of rss and atom items with json feed names
+func feedItemToJSON(item gofeed.Item, attachments []jsonFeedAttachment) map[string]interface{} {
+	data := gofeedItemToJSON(item)
+	if data == nil {
+		return nil
+	}
+	// the fields are set on items without the extensions as well so templates like {{ .item.itunes.duration }} do not fail on them
+	itunes := ext.ITunesItemExtension{}
+	if item.ITunesExt != nil {
+		itunes = *item.ITunesExt
+	}
+	dc := ext.DublinCoreExtension{}
+	if item.DublinCoreExt != nil {
+		dc = *item.DublinCoreExt
+	}
+	data["itunes"] = itunesItemToJSON(itunes, item.Extensions)
+	data["media"] = mediaToJSON(item)
+	data["dc"] = dublinCoreToJSON(dc)
+	data["ext"] = extensionsToJSON(item.Extensions)
+	data["attachments"] = attachmentsToJSON(item, attachments)
+	return data
+}
+
+// feedWithExtensionsToJSON returns the feed with "itunes" fields of podcast feeds, e.g. .feed.itunes.image
+func feedWithExtensionsToJSON(feed gofeed.Feed) map[string]interface{} {
+	data := feedToJSON(feed)
+	if data == nil || feed.ITunesExt == nil {
+		return data
+	}
+	itunes := map[string]interface{}{
+		"author":   feed.ITunesExt.Author,
+		"explicit": feed.ITunesExt.Explicit,
+		"subtitle": feed.ITunesExt.Subtitle,
+		"summary":  feed.ITunesExt.Summary,
+		"image":    feed.ITunesExt.Image,
+		"type":     extensionValue(feed.Extensions, "itunes", "type"),
+	}
+	if feed.ITunesExt.Owner != nil {
+		itunes["owner"] = map[string]interface{}{
+			"name":  feed.ITunesExt.Owner.Name,
+			"email": feed.ITunesExt.Owner.Email,
+		}
+	}
+	data["itunes"] = itunes
+	return data
+}
+
+// itunesItemToJSON returns the fields of the itunes extension of the item
+// the episode fields are not parsed by gofeed and are read from the extensions
+func itunesItemToJSON(itunes ext.ITunesItemExtension, extensions ext.Extensions) map[string]interface{} {
+	return map[string]interface{}{
+		"author":   itunes.Author,
+		"duration": itunes.Duration,
+		// durationSeconds is the duration in seconds, it is written as seconds, MM:SS or HH:MM:SS
+		"durationSeconds": itunesDurationSeconds(itunes.Duration),
+		"episode":         extensionValue(extensions, "itunes", "episode"),
+		"season":          extensionValue(extensions, "itunes", "season"),
+		"episodeType":     extensionValue(extensions, "itunes", "episodeType"),
+		"explicit":        itunes.Explicit,
+		"image":           itunes.Image,
+		"subtitle":        itunes.Subtitle,
+		"summary":         itunes.Summary,
+	}
+}
+
+// itunesDurationSeconds returns the seconds of duration like 3600, 59:30 or 1:02:03, 0 when it is not valid
+func itunesDurationSeconds(duration string) int {
+	if duration == "" {
+		return 0
+	}
+	seconds := 0
+	for _, part := range strings.Split(strings.TrimSpace(duration), ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+// mediaToJSON returns the media rss fields of the item
+// the thumbnail is the first media:thumbnail, also the ones in media:group and media:content,
+// falling back to the itunes image and the image of the item
+func mediaToJSON(item gofeed.Item) map[string]interface{} {
+	media := map[string]interface{}{}
+	elements := []ext.Extension{}
+	for _, name := range []string{"group", "content", "thumbnail"} {
+		elements = append(elements, item.Extensions["media"][name]...)
+	}
+	contents := []interface{}{}
+	thumbnail := ""
+	for len(elements) > 0 {
+		e := elements[0]
+		elements = elements[1:]
+		switch e.Name {
+		case "thumbnail":
+			if thumbnail == "" {
+				thumbnail = e.Attrs["url"]
+			}
+		case "content":
+			contents = append(contents, e.Attrs)
+		}
+		for _, name := range []string{"content", "thumbnail"} {
+			elements = append(elements, e.Children[name]...)
+		}
+	}
+	if len(contents) > 0 {
+		media["content"] = contents
+	}
+	if thumbnail == "" && item.ITunesExt != nil {
+		thumbnail = item.ITunesExt.Image
+	}
+	if thumbnail == "" && item.Image != nil {
+		thumbnail = item.Image.URL
+	}
+	if thumbnail != "" {
+		media["thumbnail"] = thumbnail
+	}
+	return media
+}
+
+// dublinCoreToJSON returns the dublin core fields, fields with several values are joined by comma
+func dublinCoreToJSON(dc ext.DublinCoreExtension) map[string]interface{} {
+	fields := map[string][]string{
+		"title":       dc.Title,
+		"creator":     dc.Creator,
+		"author":      dc.Author,
+		"subject":     dc.Subject,
+		"description": dc.Description,
+		"publisher":   dc.Publisher,
+		"contributor": dc.Contributor,
+		"date":        dc.Date,
+		"type":        dc.Type,
+		"format":      dc.Format,
+		"identifier":  dc.Identifier,
+		"source":      dc.Source,
+		"language":    dc.Language,
+		"relation":    dc.Relation,
+		"coverage":    dc.Coverage,
+		"rights":      dc.Rights,
+	}
+	res := map[string]interface{}{}
+	for name, values := range fields {
+		if len(values) > 0 {
+			res[name] = strings.Join(values, ", ")
+		}
+	}
+	return res
+}
+
+// extensionValue returns the text of the first element with the name in the namespace prefix, empty when not set
+func extensionValue(extensions ext.Extensions, prefix string, name string) string {
+	if e := extensions[prefix][name]; len(e) > 0 {
+		return e[0].Value
+	}
+	return ""
+}
+
+// extensionsToJSON returns the text of the first element of each name by namespace prefix
+// the url or href attribute is used for elements without text, e.g. <itunes:image href="..."/>
+func extensionsToJSON(extensions ext.Extensions) map[string]interface{} {
+	res := map[string]interface{}{}
+	for prefix, elements := range extensions {
+		values := map[string]interface{}{}
+		for name, e := range elements {
+			if len(e) == 0 {
+				continue
+			}
+			value := e[0].Value
+			for _, attr := range []string{"url", "href"} {
+				if value == "" {
+					value = e[0].Attrs[attr]
+				}
+			}
+			values[name] = value
+		}
+		res[prefix] = values
+	}
+	return res
+}
+
+// attachmentsToJSON returns the attachments of json feed item, or the enclosures of the item
+func attachmentsToJSON(item gofeed.Item, attachments []jsonFeedAttachment) []interface{} {
+	res := []interface{}{}
+	if attachments != nil {
+		for _, a := range attachments {
+			res = append(res, map[string]interface{}{
+				"url":                 a.URL,
+				"mime_type":           a.MimeType,
+				"title":               a.Title,
+				"size_in_bytes":       a.SizeInBytes,
+				"duration_in_seconds": int64(a.DurationInSeconds),
+			})
+		}
+		return res
+	}
+	for _, e := range item.Enclosures {
+		if e == nil {
+			continue
+		}
+		size, _ := strconv.ParseInt(e.Length, 10, 64)
+		attachment := map[string]interface{}{
+			"url":           e.URL,
+			"mime_type":     e.Type,
+			"size_in_bytes": size,
+		}
+		if item.ITunesExt != nil {
+			attachment["duration_in_seconds"] = itunesDurationSeconds(item.ITunesExt.Duration)
+		}
+		res = append(res, attachment)
+	}
+	return res
+}
@@ -0,0 +1,416 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+const (
+	testPodcastFeed = `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+<title>Pod</title>
+<item>
+<guid>ep-12</guid>
+<title>Episode 12</title>
+<itunes:duration>1:02:03</itunes:duration>
+<itunes:episode>12</itunes:episode>
+<itunes:season>2</itunes:season>
+<enclosure url="https://cdn.example.com/12.mp3" type="audio/mpeg" length="1024"/>
+<media:thumbnail url="https://cdn.example.com/12.jpg"/>
+</item>
+<item>
+<guid>post</guid>
+<title>Post</title>
+</item>
+</channel>
+</rss>`
+
+	testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Pod",
+  "authors": [{"name": "Oleg"}],
+  "items": [
+    {
+      "id": 12,
+      "title": "Episode 12",
+      "content_text": "text",
+      "date_published": "2026-10-12T08:00:00Z",
+      "attachments": [
+        {"url": "https://cdn.example.com/12.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1024, "duration_in_seconds": 3723}
+      ]
+    },
+    {
+      "id": "post",
+      "title": "Post"
+    }
+  ]
+}`
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantTitles      []string
+		wantGUIDs       []string
+		wantAttachments [][]jsonFeedAttachment
+		wantEnclosures  []int
+		wantErr         bool
+	}{
+		{
+			name:       "rss",
+			body:       testPodcastFeed,
+			wantTitles: []string{"Episode 12", "Post"},
+			wantGUIDs:  []string{"ep-12", "post"},
+			// attachments are returned for json feed only
+			wantEnclosures: []int{1, 0},
+		},
+		{
+			name:       "json feed with attachments",
+			body:       testJSONFeed,
+			wantTitles: []string{"Episode 12", "Post"},
+			wantGUIDs:  []string{"12", "post"},
+			wantAttachments: [][]jsonFeedAttachment{
+				{
+					{
+						URL:               "https://cdn.example.com/12.mp3",
+						MimeType:          "audio/mpeg",
+						SizeInBytes:       1024,
+						DurationInSeconds: 3723,
+					},
+				},
+				{},
+			},
+			wantEnclosures: []int{1, 0},
+		},
+		{
+			name:    "json that is not json feed",
+			body:    `{"version": "1", "items": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed json feed",
+			body:    `{"version": `,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, attachments, err := parseFeed(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			titles, guids, enclosures := []string{}, []string{}, []int{}
+			for _, item := range feed.Items {
+				titles = append(titles, item.Title)
+				guids = append(guids, item.GUID)
+				enclosures = append(enclosures, len(item.Enclosures))
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) {
+				t.Errorf("parseFeed() titles = %v, want %v", titles, tt.wantTitles)
+			}
+			if !reflect.DeepEqual(guids, tt.wantGUIDs) {
+				t.Errorf("parseFeed() guids = %v, want %v", guids, tt.wantGUIDs)
+			}
+			if !reflect.DeepEqual(enclosures, tt.wantEnclosures) {
+				t.Errorf("parseFeed() enclosures = %v, want %v", enclosures, tt.wantEnclosures)
+			}
+			if !reflect.DeepEqual(attachments, tt.wantAttachments) {
+				t.Errorf("parseFeed() attachments = %v, want %v", attachments, tt.wantAttachments)
+			}
+		})
+	}
+}
+
+func TestFeedItemToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// path of the field in the first item and its value
+		path []string
+		want interface{}
+	}{
+		{
+			name: "itunes duration",
+			body: testPodcastFeed,
+			path: []string{"itunes", "duration"},
+			want: "1:02:03",
+		},
+		{
+			name: "itunes duration in seconds",
+			body: testPodcastFeed,
+			path: []string{"itunes", "durationSeconds"},
+			want: 3723,
+		},
+		{
+			name: "itunes episode",
+			body: testPodcastFeed,
+			path: []string{"itunes", "episode"},
+			want: "12",
+		},
+		{
+			name: "itunes season",
+			body: testPodcastFeed,
+			path: []string{"itunes", "season"},
+			want: "2",
+		},
+		{
+			name: "media thumbnail",
+			body: testPodcastFeed,
+			path: []string{"media", "thumbnail"},
+			want: "https://cdn.example.com/12.jpg",
+		},
+		{
+			name: "text of other extension",
+			body: testPodcastFeed,
+			path: []string{"ext", "itunes", "episode"},
+			want: "12",
+		},
+		{
+			name: "json feed content text",
+			body: testJSONFeed,
+			path: []string{"content"},
+			want: "text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, attachments, err := parseFeed(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var itemAttachments []jsonFeedAttachment
+			if attachments != nil {
+				itemAttachments = attachments[0]
+			}
+			var got interface{} = feedItemToJSON(*feed.Items[0], itemAttachments)
+			for _, p := range tt.path {
+				m, ok := got.(map[string]interface{})
+				if !ok {
+					t.Fatalf("feedItemToJSON() has no %v", tt.path)
+				}
+				got = m[p]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("feedItemToJSON() %v = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedItemToJSONWithoutExtensions(t *testing.T) {
+	feed, _, err := parseFeed(testPodcastFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := feedItemToJSON(*feed.Items[1], nil)
+	// templates like {{ .item.itunes.duration }} do not fail on items without the extensions
+	for _, name := range []string{"itunes", "media", "dc", "ext"} {
+		if _, ok := got[name].(map[string]interface{}); !ok {
+			t.Errorf("feedItemToJSON() %s = %v", name, got[name])
+		}
+	}
+	if a, ok := got["attachments"].([]interface{}); !ok || len(a) != 0 {
+		t.Errorf("feedItemToJSON() attachments = %v", got["attachments"])
+	}
+}
+
+func TestAttachmentsToJSON(t *testing.T) {
+	enclosure := &gofeed.Enclosure{
+		URL:    "https://cdn.example.com/12.mp3",
+		Type:   "audio/mpeg",
+		Length: "1024",
+	}
+	tests := []struct {
+		name        string
+		item        gofeed.Item
+		attachments []jsonFeedAttachment
+		want        []interface{}
+	}{
+		{
+			name: "attachments of json feed",
+			item: gofeed.Item{
+				Enclosures: []*gofeed.Enclosure{enclosure},
+			},
+			attachments: []jsonFeedAttachment{
+				{
+					URL:               "https://cdn.example.com/12.mp3",
+					MimeType:          "audio/mpeg",
+					Title:             "Episode 12",
+					SizeInBytes:       1024,
+					DurationInSeconds: 3723.5,
+				},
+			},
+			want: []interface{}{
+				map[string]interface{}{
+					"url":                 "https://cdn.example.com/12.mp3",
+					"mime_type":           "audio/mpeg",
+					"title":               "Episode 12",
+					"size_in_bytes":       int64(1024),
+					"duration_in_seconds": int64(3723),
+				},
+			},
+		},
+		{
+			name: "enclosures",
+			item: gofeed.Item{
+				Enclosures: []*gofeed.Enclosure{enclosure, nil},
+			},
+			want: []interface{}{
+				map[string]interface{}{
+					"url":           "https://cdn.example.com/12.mp3",
+					"mime_type":     "audio/mpeg",
+					"size_in_bytes": int64(1024),
+				},
+			},
+		},
+		{
+			name: "enclosures with itunes duration",
+			item: gofeed.Item{
+				Enclosures: []*gofeed.Enclosure{enclosure},
+				ITunesExt: &ext.ITunesItemExtension{
+					Duration: "59:30",
+				},
+			},
+			want: []interface{}{
+				map[string]interface{}{
+					"url":                 "https://cdn.example.com/12.mp3",
+					"mime_type":           "audio/mpeg",
+					"size_in_bytes":       int64(1024),
+					"duration_in_seconds": 3570,
+				},
+			},
+		},
+		{
+			name: "enclosure with invalid length",
+			item: gofeed.Item{
+				Enclosures: []*gofeed.Enclosure{{URL: "https://cdn.example.com/12.mp3", Length: "1 MB"}},
+			},
+			want: []interface{}{
+				map[string]interface{}{
+					"url":           "https://cdn.example.com/12.mp3",
+					"mime_type":     "",
+					"size_in_bytes": int64(0),
+				},
+			},
+		},
+		{
+			name: "item without enclosures",
+			item: gofeed.Item{},
+			want: []interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attachmentsToJSON(tt.item, tt.attachments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attachmentsToJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItunesDurationSeconds(t *testing.T) {
+	tests := []struct {
+		duration string
+		want     int
+	}{
+		{duration: "3600", want: 3600},
+		{duration: "59:30", want: 3570},
+		{duration: "1:02:03", want: 3723},
+		{duration: " 01:02:03 ", want: 3723},
+		{duration: "", want: 0},
+		{duration: "1h2m", want: 0},
+		{duration: "1:xx", want: 0},
+		{duration: "1::3", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration, func(t *testing.T) {
+			if got := itunesDurationSeconds(tt.duration); got != tt.want {
+				t.Errorf("itunesDurationSeconds(%q) = %d, want %d", tt.duration, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaToJSON(t *testing.T) {
+	element := func(name string, url string, children ...ext.Extension) ext.Extension {
+		e := ext.Extension{
+			Name:     name,
+			Attrs:    map[string]string{"url": url},
+			Children: map[string][]ext.Extension{},
+		}
+		for _, c := range children {
+			e.Children[c.Name] = append(e.Children[c.Name], c)
+		}
+		return e
+	}
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want map[string]interface{}
+	}{
+		{
+			name: "thumbnail",
+			item: gofeed.Item{
+				Extensions: ext.Extensions{"media": {"thumbnail": {element("thumbnail", "t.jpg")}}},
+			},
+			want: map[string]interface{}{"thumbnail": "t.jpg"},
+		},
+		{
+			name: "content with thumbnail",
+			item: gofeed.Item{
+				Extensions: ext.Extensions{"media": {"content": {element("content", "v.mp4", element("thumbnail", "v.jpg"))}}},
+			},
+			want: map[string]interface{}{
+				"thumbnail": "v.jpg",
+				"content":   []interface{}{map[string]string{"url": "v.mp4"}},
+			},
+		},
+		{
+			name: "group of contents",
+			item: gofeed.Item{
+				Extensions: ext.Extensions{"media": {"group": {element("group", "", element("content", "a.mp4"), element("content", "b.mp4"), element("thumbnail", "g.jpg"))}}},
+			},
+			want: map[string]interface{}{
+				"thumbnail": "g.jpg",
+				"content": []interface{}{
+					map[string]string{"url": "a.mp4"},
+					map[string]string{"url": "b.mp4"},
+				},
+			},
+		},
+		{
+			name: "itunes image",
+			item: gofeed.Item{
+				ITunesExt: &ext.ITunesItemExtension{Image: "i.jpg"},
+				Image:     &gofeed.Image{URL: "item.jpg"},
+			},
+			want: map[string]interface{}{"thumbnail": "i.jpg"},
+		},
+		{
+			name: "image of the item",
+			item: gofeed.Item{
+				Image: &gofeed.Image{URL: "item.jpg"},
+			},
+			want: map[string]interface{}{"thumbnail": "item.jpg"},
+		},
+		{
+			name: "no media",
+			item: gofeed.Item{},
+			want: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mediaToJSON(tt.item); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mediaToJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type (
+	// builtinFilter returns true when the item passed the filter
+	builtinFilter func(meta ItemMeta, now time.Time) bool
+)
+
+// buildBuiltinFilters returns the built-in filters configured on the source
+func buildBuiltinFilters(src Source) ([]builtinFilter, error) {
+	filters := []builtinFilter{}
+	if src.MaxAge != "" {
+		d, err := time.ParseDuration(src.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid max-age: %w", err)
+		}
+		filters = append(filters, func(meta ItemMeta, now time.Time) bool {
+			return meta.Published != nil && now.Sub(*meta.Published) <= d
+		})
+	}
+	if len(src.TitleMatches) > 0 {
+		res, err := compileRegexps("title-matches", src.TitleMatches)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(meta ItemMeta, now time.Time) bool {
+			return matchAny(res, meta.Title)
+		})
+	}
+	if len(src.TitleExcludes) > 0 {
+		res, err := compileRegexps("title-excludes", src.TitleExcludes)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(meta ItemMeta, now time.Time) bool {
+			return !matchAny(res, meta.Title)
+		})
+	}
+	if len(src.CategoriesAny) > 0 {
+		filters = append(filters, func(meta ItemMeta, now time.Time) bool {
+			return containsAny(src.CategoriesAny, meta.Categories)
+		})
+	}
+	if len(src.AuthorIn) > 0 {
+		filters = append(filters, func(meta ItemMeta, now time.Time) bool {
+			return containsAny(src.AuthorIn, meta.Authors)
+		})
+	}
+	return filters, nil
+}
+
+// passBuiltinFilters returns true when the item passed all the filters
+func passBuiltinFilters(filters []builtinFilter, meta ItemMeta) bool {
+	now := time.Now()
+	for _, f := range filters {
+		if !f(meta, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func compileRegexps(name string, exprs []string) ([]*regexp.Regexp, error) {
+	res := []*regexp.Regexp{}
+	for _, e := range exprs {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s expression \"%s\": %w", name, e, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny returns true when any of the values is one of the list, case insensitive
+func containsAny(list []string, values []string) bool {
+	for _, l := range list {
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(l), strings.TrimSpace(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
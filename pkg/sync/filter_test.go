@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuiltinFilters(t *testing.T) {
+	ago := func(d time.Duration) *time.Time {
+		t := time.Now().Add(-d)
+		return &t
+	}
+	tests := []struct {
+		name string
+		src  Source
+		meta ItemMeta
+		want bool
+	}{
+		{
+			name: "no filters",
+			src:  Source{},
+			meta: ItemMeta{},
+			want: true,
+		},
+		{
+			name: "max-age of new item",
+			src:  Source{MaxAge: "24h"},
+			meta: ItemMeta{Published: ago(time.Hour)},
+			want: true,
+		},
+		{
+			name: "max-age of old item",
+			src:  Source{MaxAge: "24h"},
+			meta: ItemMeta{Published: ago(48 * time.Hour)},
+			want: false,
+		},
+		{
+			name: "max-age of item without date",
+			src:  Source{MaxAge: "24h"},
+			meta: ItemMeta{},
+			want: false,
+		},
+		{
+			name: "title-matches any of the expressions",
+			src:  Source{TitleMatches: []string{"^Release", "(?i)security"}},
+			meta: ItemMeta{Title: "Fix SECURITY issue"},
+			want: true,
+		},
+		{
+			name: "title-matches none of the expressions",
+			src:  Source{TitleMatches: []string{"^Release"}},
+			meta: ItemMeta{Title: "Weekly update"},
+			want: false,
+		},
+		{
+			name: "title-excludes",
+			src:  Source{TitleExcludes: []string{"(?i)sponsored"}},
+			meta: ItemMeta{Title: "Sponsored: buy now"},
+			want: false,
+		},
+		{
+			name: "title-excludes of item without title",
+			src:  Source{TitleExcludes: []string{"sponsored"}},
+			meta: ItemMeta{},
+			want: true,
+		},
+		{
+			name: "categories-any is case insensitive",
+			src:  Source{CategoriesAny: []string{"Go", "rust"}},
+			meta: ItemMeta{Categories: []string{"news", " go "}},
+			want: true,
+		},
+		{
+			name: "categories-any without match",
+			src:  Source{CategoriesAny: []string{"go"}},
+			meta: ItemMeta{Categories: []string{"golang"}},
+			want: false,
+		},
+		{
+			name: "author-in",
+			src:  Source{AuthorIn: []string{"jane@example.com"}},
+			meta: ItemMeta{Authors: []string{"Jane", "jane@example.com"}},
+			want: true,
+		},
+		{
+			name: "all the filters must pass",
+			src:  Source{MaxAge: "24h", TitleMatches: []string{"Go"}},
+			meta: ItemMeta{Title: "Go 1.14", Published: ago(48 * time.Hour)},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := buildBuiltinFilters(tt.src)
+			if err != nil {
+				t.Fatalf("buildBuiltinFilters() error = %v", err)
+			}
+			if got := passBuiltinFilters(filters, tt.meta); got != tt.want {
+				t.Errorf("passBuiltinFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildBuiltinFiltersErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  Source
+	}{
+		{
+			name: "invalid max-age",
+			src:  Source{MaxAge: "1 day"},
+		},
+		{
+			name: "invalid title-matches",
+			src:  Source{TitleMatches: []string{"("}},
+		},
+		{
+			name: "invalid title-excludes",
+			src:  Source{TitleExcludes: []string{"["}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildBuiltinFilters(tt.src); err == nil {
+				t.Errorf("buildBuiltinFilters() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+type (
+	htmlSource struct {
+		src Source
+	}
+)
+
+var (
+	// htmlDateFormats are tried in order when the source has no date format
+	htmlDateFormats = []string{
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		"January 2, 2006",
+		"Jan 2, 2006",
+		"2 January 2006",
+		"02 Jan 2006",
+		"02/01/2006",
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindHTML, func(src Source) (SourceKind, error) {
+		if src.HTML == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no html config", src.Name)
+		}
+		return &htmlSource{src: src}, nil
+	})
+}
+
+// Fetch returns element of the page the item selector matched as item with "item" and "feed" data
+// the item has the same fields as item of rss source, the feed has the title and the link of the page
+func (s *htmlSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	u, err := buildURL(s.src.HTML.URL, "", "")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Failed to build URL from %s: %w", s.src.HTML.URL, err)
+	}
+	res, st, err := fetchURL(ctx, req, u, conditionalGetEnabled(s.src))
+	if err != nil {
+		return FetchResult{}, err
+	}
+	result := FetchResult{
+		Status: res.Status,
+		State:  st,
+	}
+	if err := readHTTPResponse(res); err != nil {
+		return result, err
+	}
+	if notModified(res) {
+		result.NotModified = true
+		return result, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(res.Body))
+	if err != nil {
+		return result, fmt.Errorf("Failed to parse page: %w", err)
+	}
+	base, err := url.Parse(u)
+	if err != nil {
+		return result, err
+	}
+	feed := gofeed.Feed{
+		Title: strings.TrimSpace(doc.Find("title").First().Text()),
+		Link:  u,
+	}
+	feedValues := feedToJSON(feed)
+	doc.Find(s.src.HTML.Item).Each(func(i int, sel *goquery.Selection) {
+		item := s.scrape(sel, base)
+		result.Items = append(result.Items, Item{
+			Key:  gofeedItemKey(item),
+			Name: item.Title,
+			Data: map[string]interface{}{
+				"item": feedItemToJSON(item, nil),
+				"feed": feedValues,
+			},
+			Meta: gofeedItemMeta(item),
+		})
+	})
+	return result, nil
+}
+
+// scrape returns the item the selectors found in the element
+func (s *htmlSource) scrape(sel *goquery.Selection, base *url.URL) gofeed.Item {
+	cnf := s.src.HTML
+	item := gofeed.Item{
+		Title:       selectText(sel, cnf.Title),
+		Description: selectText(sel, cnf.Description),
+	}
+	link := sel.Find("a[href]").First()
+	if cnf.Link != "" {
+		link = sel.Find(cnf.Link).First()
+		if _, ok := link.Attr("href"); !ok {
+			link = link.Find("a[href]").First()
+		}
+	} else if _, ok := sel.Attr("href"); ok {
+		link = sel
+	}
+	if href, ok := link.Attr("href"); ok {
+		if ref, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			item.Link = ref.String()
+		}
+	}
+	if cnf.Title == "" {
+		item.Title = strings.Join(strings.Fields(link.Text()), " ")
+	}
+	if cnf.Date != "" {
+		date := sel.Find(cnf.Date).First()
+		value, ok := date.Attr("datetime")
+		if !ok {
+			value = date.Text()
+		}
+		item.Published = strings.TrimSpace(value)
+		if t, ok := parseHTMLDate(item.Published, cnf.DateFormat); ok {
+			item.PublishedParsed = &t
+		}
+	}
+	// items of the page have no guid, the link identifies them
+	item.GUID = item.Link
+	if item.GUID == "" && item.Title == "" {
+		item.GUID = htmlElementKey(sel)
+	}
+	return item
+}
+
+// htmlElementKey returns hash of the text of the element, or of its html when it has no text
+// it identifies elements without link and title
+func htmlElementKey(sel *goquery.Selection) string {
+	content := strings.Join(strings.Fields(sel.Text()), " ")
+	if content == "" {
+		content, _ = goquery.OuterHtml(sel)
+	}
+	h := sha1.Sum([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+// selectText returns the text of the first element the selector matched, empty when the selector is not set
+func selectText(sel *goquery.Selection, selector string) string {
+	if selector == "" {
+		return ""
+	}
+	return strings.Join(strings.Fields(sel.Find(selector).First().Text()), " ")
+}
+
+func parseHTMLDate(value string, format string) (time.Time, bool) {
+	formats := htmlDateFormats
+	if format != "" {
+		formats = []string{format}
+	}
+	for _, f := range formats {
+		if t, err := time.Parse(f, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,227 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v2"
+)
+
+const testHTMLPage = `<html>
+<head><title> Blog </title></head>
+<body>
+<ul class="posts">
+  <li class="post">
+    <h2><a href="/blog/go-1-14">Go 1.14 is released</a></h2>
+    <time datetime="2026-02-25T10:00:00Z">Feb 25</time>
+    <p class="summary">Modules are ready for production</p>
+  </li>
+  <li class="post">
+    <h2><a href="generics">Generics  draft</a></h2>
+    <span class="date">June 16, 2026</span>
+  </li>
+  <li class="post">
+    <h2>Without link</h2>
+    <span class="date">not a date</span>
+  </li>
+  <li class="post"><img src="/banner.png"></li>
+</ul>
+</body>
+</html>`
+
+func TestHTMLElementKey(t *testing.T) {
+	key := func(html string) string {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<ul>" + html + "</ul>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return htmlElementKey(doc.Find("li").First())
+	}
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		same bool
+	}{
+		{
+			name: "same text",
+			a:    "<li>Release 1.0</li>",
+			b:    "<li>  Release\n 1.0 </li>",
+			same: true,
+		},
+		{
+			name: "same text in other elements",
+			a:    "<li><b>Release</b> 1.0</li>",
+			b:    "<li>Release 1.0</li>",
+			same: true,
+		},
+		{
+			name: "other text",
+			a:    "<li>Release 1.0</li>",
+			b:    "<li>Release 1.1</li>",
+			same: false,
+		},
+		{
+			name: "elements without text by their html",
+			a:    `<li><img src="a.png"></li>`,
+			b:    `<li><img src="b.png"></li>`,
+			same: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := key(tt.a), key(tt.b)
+			if a == "" || b == "" {
+				t.Fatalf("htmlElementKey() is empty")
+			}
+			if (a == b) != tt.same {
+				t.Errorf("htmlElementKey() = %s and %s, same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestHTMLSourceFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testHTMLPage))
+	}))
+	defer srv.Close()
+	type item struct {
+		Title       string
+		Link        string
+		Description string
+		Published   string
+		Parsed      string
+	}
+	tests := []struct {
+		name   string
+		config string
+		want   []item
+	}{
+		{
+			name:   "selectors",
+			config: "item: li.post\ntitle: h2\ndate: time, .date\ndescription: .summary",
+			want: []item{
+				{
+					Title:       "Go 1.14 is released",
+					Link:        srv.URL + "/blog/go-1-14",
+					Description: "Modules are ready for production",
+					Published:   "2026-02-25T10:00:00Z",
+					Parsed:      "2026-02-25T10:00:00Z",
+				},
+				{
+					// relative to the url of the page
+					Title:     "Generics draft",
+					Link:      srv.URL + "/blog/generics",
+					Published: "June 16, 2026",
+					Parsed:    "2026-06-16T00:00:00Z",
+				},
+				{
+					Title:     "Without link",
+					Published: "not a date",
+				},
+				{},
+			},
+		},
+		{
+			name:   "title of the link by default",
+			config: "item: li.post\nlink: h2",
+			want: []item{
+				{
+					Title: "Go 1.14 is released",
+					Link:  srv.URL + "/blog/go-1-14",
+				},
+				{
+					Title: "Generics draft",
+					Link:  srv.URL + "/blog/generics",
+				},
+				{},
+				{},
+			},
+		},
+		{
+			name:   "date format",
+			config: "item: li.post\ntitle: h2\ndate: .date\ndate-format: January 2, 2006",
+			want: []item{
+				{
+					Title: "Go 1.14 is released",
+					Link:  srv.URL + "/blog/go-1-14",
+				},
+				{
+					Title:     "Generics draft",
+					Link:      srv.URL + "/blog/generics",
+					Published: "June 16, 2026",
+					Parsed:    "2026-06-16T00:00:00Z",
+				},
+				{
+					Title:     "Without link",
+					Published: "not a date",
+				},
+				{},
+			},
+		},
+		{
+			name:   "item selector that matches nothing",
+			config: "item: article",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{}
+			if err := yaml.Unmarshal([]byte("name: blog\nhtml:\n  url: "+srv.URL+"/blog/\n  "+strings.ReplaceAll(tt.config, "\n", "\n  ")), &src); err != nil {
+				t.Fatal(err)
+			}
+			kind, err := buildSourceKind(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := kind.Fetch(context.Background(), FetchRequest{
+				Caller: &nativeCaller{client: srv.Client()},
+				State:  map[string]string{},
+			})
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			got := []item{}
+			keys := map[string]bool{}
+			for _, i := range res.Items {
+				data := i.Data["item"].(map[string]interface{})
+				// empty fields are not set
+				field := func(name string) string {
+					v, _ := data[name].(string)
+					return v
+				}
+				it := item{
+					Title:       field("title"),
+					Link:        field("link"),
+					Description: field("description"),
+					Published:   field("published"),
+				}
+				if i.Meta.Published != nil {
+					it.Parsed = i.Meta.Published.Format(time.RFC3339)
+				}
+				got = append(got, it)
+				if i.Key == "" || keys[i.Key] {
+					t.Errorf("Fetch() key %q of %+v is empty or not unique", i.Key, it)
+				}
+				keys[i.Key] = true
+				if i.Data["feed"].(map[string]interface{})["title"] != "Blog" {
+					t.Errorf("Fetch() feed = %v", i.Data["feed"])
+				}
+			}
+			want := tt.want
+			if want == nil {
+				want = []item{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Fetch() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
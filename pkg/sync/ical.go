@@ -0,0 +1,576 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	// icalHorizon limits the expansion of recurring events of source without time-max
+	icalHorizon = 365 * 24 * time.Hour
+
+	icalDateFormat     = "20060102"
+	icalDateTimeFormat = "20060102T150405"
+)
+
+type (
+	icalSource struct {
+		src Source
+	}
+
+	// icalProperty is one content line of the calendar, e.g. DTSTART;TZID=Europe/London:20200601T100000
+	icalProperty struct {
+		name   string
+		params map[string]string
+		value  string
+	}
+
+	// icalEvent is the properties of one VEVENT, multi value properties like EXDATE are kept in order
+	icalEvent struct {
+		props map[string][]icalProperty
+		// zones are the locations of the VTIMEZONEs of the calendar by TZID
+		zones map[string]*time.Location
+	}
+
+	// icalZone is the properties of one VTIMEZONE
+	icalZone struct {
+		tzid string
+		// location is the IANA name some calendars add, e.g. X-LIC-LOCATION:Europe/London
+		location string
+		// offset is the TZOFFSETTO of the standard time, e.g. +0100
+		offset string
+	}
+
+	// icalTime is the value of date or date-time property
+	icalTime struct {
+		t      time.Time
+		allDay bool
+		tzid   string
+	}
+)
+
+var (
+	icalDurationRegexp = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+)
+
+func init() {
+	RegisterSource(sourceKindICal, func(src Source) (SourceKind, error) {
+		if src.ICal == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no ical config", src.Name)
+		}
+		return &icalSource{src: src}, nil
+	})
+}
+
+// Fetch returns the events between time-min and time-max with "event" data
+// recurring events are expanded to item per occurrence like the google-calendar source does
+func (s *icalSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	u, err := buildURL(s.src.ICal.URL, "", "")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Failed to build URL from %s: %w", s.src.ICal.URL, err)
+	}
+	min, max, err := icalWindow(template.String(&s.src.ICal.TimeMin, nil), template.String(&s.src.ICal.TimeMax, nil))
+	if err != nil {
+		return FetchResult{}, err
+	}
+	res, st, err := fetchURL(ctx, req, u, conditionalGetEnabled(s.src))
+	if err != nil {
+		return FetchResult{}, err
+	}
+	// the events are the ones of the time window, events that end are not removed
+	result := FetchResult{
+		Status:  res.Status,
+		State:   st,
+		Partial: true,
+	}
+	if err := readHTTPResponse(res); err != nil {
+		return result, err
+	}
+	if notModified(res) {
+		result.NotModified = true
+		return result, nil
+	}
+	events, err := parseICal(res.Body)
+	if err != nil {
+		return result, fmt.Errorf("Failed to parse calendar: %w", err)
+	}
+	occurrences, err := expandICalEvents(events, min, max)
+	if err != nil {
+		return result, err
+	}
+	for _, ev := range occurrences {
+		result.Items = append(result.Items, Item{
+			Key: fmt.Sprintf("%v", ev["id"]),
+			Data: map[string]interface{}{
+				"event": ev,
+			},
+			Meta:    icalEventMeta(ev),
+			Removed: ev["status"] == googleCalendarStatusCancelled,
+		})
+	}
+	return result, nil
+}
+
+// icalWindow parses the RFC3339 limits of the window, time-max defaults to a year after time-min
+func icalWindow(timeMin string, timeMax string) (time.Time, time.Time, error) {
+	min := time.Now()
+	if timeMin != "" {
+		t, err := time.Parse(time.RFC3339, timeMin)
+		if err != nil {
+			return min, min, fmt.Errorf("Failed to parse time-min: %w", err)
+		}
+		min = t
+	}
+	max := min.Add(icalHorizon)
+	if timeMax != "" {
+		t, err := time.Parse(time.RFC3339, timeMax)
+		if err != nil {
+			return min, max, fmt.Errorf("Failed to parse time-max: %w", err)
+		}
+		max = t
+	}
+	return min, max, nil
+}
+
+// parseICal returns the VEVENTs of the calendar, components nested in the events like VALARM are skipped
+// the TZIDs of the events are resolved with the VTIMEZONEs of the calendar
+func parseICal(body string) ([]icalEvent, error) {
+	events := []icalEvent{}
+	zones := map[string]*time.Location{}
+	var current *icalEvent
+	var zone *icalZone
+	// component is the STANDARD or DAYLIGHT component of the current VTIMEZONE
+	component := ""
+	depth := 0
+	for _, line := range unfoldICal(body) {
+		p, err := parseICalProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VTIMEZONE") && current == nil:
+			zone = &icalZone{}
+		case p.name == "END" && strings.EqualFold(p.value, "VTIMEZONE") && zone != nil:
+			if l, ok := zone.resolve(); ok {
+				zones[zone.tzid] = l
+			}
+			zone = nil
+		case p.name == "BEGIN" && zone != nil:
+			component = strings.ToUpper(p.value)
+		case p.name == "END" && zone != nil:
+			component = ""
+		case zone != nil:
+			switch {
+			case p.name == "TZID" && component == "":
+				zone.tzid = p.value
+			case p.name == "X-LIC-LOCATION" && component == "":
+				zone.location = p.value
+			case p.name == "TZOFFSETTO" && (component == "STANDARD" || zone.offset == ""):
+				zone.offset = p.value
+			}
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && current == nil:
+			current = &icalEvent{props: map[string][]icalProperty{}}
+		case p.name == "BEGIN" && current != nil:
+			depth++
+		case p.name == "END" && current != nil && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && current != nil:
+			current.zones = zones
+			events = append(events, *current)
+			current = nil
+		case current != nil && depth == 0:
+			current.props[p.name] = append(current.props[p.name], p)
+		}
+	}
+	return events, nil
+}
+
+// unfoldICal joins the content lines that were folded to several lines
+func unfoldICal(body string) []string {
+	lines := []string{}
+	for _, l := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+func parseICalProperty(line string) (icalProperty, error) {
+	// the value starts at the first colon that is not quoted in the parameters
+	quoted := false
+	sep := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return icalProperty{}, fmt.Errorf("Invalid line \"%s\"", line)
+	}
+	parts := strings.Split(line[:sep], ";")
+	p := icalProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  line[sep+1:],
+	}
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, nil
+}
+
+func (e icalEvent) get(name string) (icalProperty, bool) {
+	props := e.props[name]
+	if len(props) == 0 {
+		return icalProperty{}, false
+	}
+	return props[0], true
+}
+
+// text returns the unescaped value of text property
+func (e icalEvent) text(name string) string {
+	p, ok := e.get(name)
+	if !ok {
+		return ""
+	}
+	return icalUnescape(p.value)
+}
+
+// list returns the unescaped values of all the properties with the name, e.g. CATEGORIES:Work,Q\,A
+// the values are separated by commas that are not escaped
+func (e icalEvent) list(name string) []string {
+	res := []string{}
+	for _, p := range e.props[name] {
+		start := 0
+		for i := 0; i < len(p.value); i++ {
+			switch p.value[i] {
+			case '\\':
+				i++
+			case ',':
+				res = append(res, icalUnescape(p.value[start:i]))
+				start = i + 1
+			}
+		}
+		res = append(res, icalUnescape(p.value[start:]))
+	}
+	return res
+}
+
+func icalUnescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func (e icalEvent) time(name string) (icalTime, bool, error) {
+	p, ok := e.get(name)
+	if !ok {
+		return icalTime{}, false, nil
+	}
+	t, err := parseICalTime(p.value, p.params, e.zones)
+	if err != nil {
+		return icalTime{}, false, fmt.Errorf("Failed to parse %s of event %s: %w", name, e.text("UID"), err)
+	}
+	return t, true, nil
+}
+
+// times returns the values of all the properties with the name, e.g. EXDATE:20200601T100000Z,20200608T100000Z
+func (e icalEvent) times(name string) ([]time.Time, error) {
+	res := []time.Time{}
+	for _, p := range e.props[name] {
+		for _, v := range strings.Split(p.value, ",") {
+			t, err := parseICalTime(v, p.params, e.zones)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse %s of event %s: %w", name, e.text("UID"), err)
+			}
+			res = append(res, t.t)
+		}
+	}
+	return res, nil
+}
+
+// parseICalTime parses date (all day) or date-time value in UTC, in the TZID of the property or floating in the local time
+// the TZID is looked up in the zones of the calendar, then by its IANA or windows name
+func parseICalTime(value string, params map[string]string, zones map[string]*time.Location) (icalTime, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(icalDateFormat) {
+		t, err := time.ParseInLocation(icalDateFormat, value, time.Local)
+		return icalTime{t: t, allDay: true}, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalDateTimeFormat+"Z", value)
+		return icalTime{t: t}, err
+	}
+	loc := time.Local
+	tzid := params["TZID"]
+	if tzid != "" {
+		l, ok := zones[tzid]
+		if !ok {
+			l, ok = loadICalLocation(tzid)
+		}
+		if !ok {
+			return icalTime{}, fmt.Errorf("Unknown time zone \"%s\"", tzid)
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation(icalDateTimeFormat, value, loc)
+	return icalTime{t: t, tzid: tzid}, err
+}
+
+// parseICalDuration parses the DURATION of event, e.g. PT1H30M
+func parseICalDuration(value string) (time.Duration, error) {
+	m := icalDurationRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("Invalid duration \"%s\"", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	d := time.Duration(0)
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// expandICalEvents returns the occurrences of the events that overlap the window, sorted by start
+// occurrences that were modified (RECURRENCE-ID) replace the occurrence of the recurring event
+func expandICalEvents(events []icalEvent, min time.Time, max time.Time) ([]map[string]interface{}, error) {
+	type occurrence struct {
+		start time.Time
+		event map[string]interface{}
+	}
+	overrides := map[string]bool{}
+	for _, e := range events {
+		if id, ok, err := e.time("RECURRENCE-ID"); err == nil && ok {
+			overrides[e.text("UID")+seperator+id.t.UTC().Format(time.RFC3339)] = true
+		}
+	}
+	res := []occurrence{}
+	for _, e := range events {
+		start, ok, err := e.time("DTSTART")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		duration, err := icalEventDuration(e, start)
+		if err != nil {
+			return nil, err
+		}
+		uid := e.text("UID")
+		starts := []time.Time{start.t}
+		_, recurring := e.get("RRULE")
+		if recurring {
+			starts, err = expandICalRule(e, start, min.Add(-duration), max)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// the occurrence keeps the id of the original start once it was modified
+		original, modified, err := e.time("RECURRENCE-ID")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range starts {
+			if recurring && overrides[uid+seperator+s.UTC().Format(time.RFC3339)] {
+				continue
+			}
+			end := s.Add(duration)
+			// events without duration are in the window when they start in it
+			if !s.Before(max) || end.Before(min) || (end.Equal(min) && duration > 0) {
+				continue
+			}
+			id := time.Time{}
+			switch {
+			case modified:
+				id = original.t
+			case recurring:
+				id = s
+			}
+			res = append(res, occurrence{
+				start: s,
+				event: icalEventToJSON(e, icalTime{t: s, allDay: start.allDay, tzid: start.tzid}, icalTime{t: end, allDay: start.allDay, tzid: start.tzid}, id),
+			})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].start.Before(res[j].start)
+	})
+	occurrences := []map[string]interface{}{}
+	for _, o := range res {
+		occurrences = append(occurrences, o.event)
+	}
+	return occurrences, nil
+}
+
+func icalEventDuration(e icalEvent, start icalTime) (time.Duration, error) {
+	end, ok, err := e.time("DTEND")
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return end.t.Sub(start.t), nil
+	}
+	if p, ok := e.get("DURATION"); ok {
+		return parseICalDuration(p.value)
+	}
+	if start.allDay {
+		return 24 * time.Hour, nil
+	}
+	return 0, nil
+}
+
+// expandICalRule returns the starts of the occurrences of the recurring event between after and before
+func expandICalRule(e icalEvent, start icalTime, after time.Time, before time.Time) ([]time.Time, error) {
+	set := &rrule.Set{}
+	for _, p := range e.props["RRULE"] {
+		opt, err := rrule.StrToROptionInLocation(p.value, start.t.Location())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse RRULE of event %s: %w", e.text("UID"), err)
+		}
+		opt.Dtstart = start.t
+		r, err := rrule.NewRRule(*opt)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse RRULE of event %s: %w", e.text("UID"), err)
+		}
+		set.RRule(r)
+	}
+	rdates, err := e.times("RDATE")
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range rdates {
+		set.RDate(t)
+	}
+	exdates, err := e.times("EXDATE")
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range exdates {
+		set.ExDate(t)
+	}
+	return set.Between(after, before, true), nil
+}
+
+// icalEventToJSON returns the occurrence with the field names of google calendar event
+// original is the start the occurrence of recurring event was scheduled at, zero for single events
+func icalEventToJSON(e icalEvent, start icalTime, end icalTime, original time.Time) map[string]interface{} {
+	uid := e.text("UID")
+	ev := map[string]interface{}{
+		"id":      uid,
+		"iCalUID": uid,
+		"summary": e.text("SUMMARY"),
+		"status":  "confirmed",
+		"start":   icalTimeToJSON(start),
+		"end":     icalTimeToJSON(end),
+	}
+	if !original.IsZero() {
+		// same id format as the occurrences of google calendar recurring events
+		ev["id"] = fmt.Sprintf("%s_%s", uid, original.UTC().Format(icalDateTimeFormat+"Z"))
+		ev["recurringEventId"] = uid
+	}
+	if s := e.text("STATUS"); s != "" {
+		ev["status"] = strings.ToLower(s)
+	}
+	for name, key := range map[string]string{
+		"DESCRIPTION": "description",
+		"LOCATION":    "location",
+		"URL":         "htmlLink",
+	} {
+		if s := e.text(name); s != "" {
+			ev[key] = s
+		}
+	}
+	for name, key := range map[string]string{
+		"CREATED":       "created",
+		"LAST-MODIFIED": "updated",
+	} {
+		if t, ok, err := e.time(name); err == nil && ok {
+			ev[key] = t.t.Format(time.RFC3339)
+		}
+	}
+	if p, ok := e.get("ORGANIZER"); ok {
+		organizer := map[string]interface{}{
+			"email": strings.TrimPrefix(strings.TrimPrefix(p.value, "mailto:"), "MAILTO:"),
+		}
+		if cn := p.params["CN"]; cn != "" {
+			organizer["displayName"] = cn
+		}
+		ev["organizer"] = organizer
+	}
+	if c := e.list("CATEGORIES"); len(c) > 0 {
+		ev["categories"] = c
+	}
+	return ev
+}
+
+func icalTimeToJSON(t icalTime) map[string]interface{} {
+	if t.allDay {
+		return map[string]interface{}{
+			"date": t.t.Format("2006-01-02"),
+		}
+	}
+	res := map[string]interface{}{
+		"dateTime": t.t.Format(time.RFC3339),
+	}
+	if t.tzid != "" {
+		res["timeZone"] = t.tzid
+	}
+	return res
+}
+
+func icalEventMeta(ev map[string]interface{}) ItemMeta {
+	meta := ItemMeta{
+		GUID: fmt.Sprintf("%v", ev["id"]),
+	}
+	if s, ok := ev["summary"].(string); ok {
+		meta.Title = s
+	}
+	if s, ok := ev["htmlLink"].(string); ok {
+		meta.Link = s
+	}
+	if s, ok := ev["created"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			meta.Published = &t
+		}
+	}
+	if c, ok := ev["categories"].([]string); ok {
+		meta.Categories = c
+	}
+	if o, ok := ev["organizer"].(map[string]interface{}); ok {
+		for _, k := range []string{"displayName", "email"} {
+			if s, ok := o[k].(string); ok && s != "" {
+				meta.Authors = append(meta.Authors, s)
+			}
+		}
+	}
+	return meta
+}
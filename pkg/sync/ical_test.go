@@ -0,0 +1,382 @@
+package sync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnfoldICal(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "crlf line endings",
+			body: "BEGIN:VEVENT\r\nUID:1\r\nEND:VEVENT\r\n",
+			want: []string{"BEGIN:VEVENT", "UID:1", "END:VEVENT"},
+		},
+		{
+			name: "line folded with space",
+			body: "SUMMARY:Long\r\n  summary\r\nUID:1",
+			want: []string{"SUMMARY:Long summary", "UID:1"},
+		},
+		{
+			name: "line folded with tab several times",
+			body: "DESCRIPTION:a\n\tb\n\tc",
+			want: []string{"DESCRIPTION:abc"},
+		},
+		{
+			name: "empty lines are dropped",
+			body: "UID:1\n\n\r\nSUMMARY:s\n",
+			want: []string{"UID:1", "SUMMARY:s"},
+		},
+		{
+			name: "folded first line is kept",
+			body: " UID:1",
+			want: []string{" UID:1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unfoldICal(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unfoldICal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICalTime(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+	tests := []struct {
+		name   string
+		value  string
+		params map[string]string
+		zones  map[string]*time.Location
+		want   time.Time
+		allDay bool
+		tzid   string
+		// wantErr is set for time zones that are not known
+		wantErr bool
+	}{
+		{
+			name:  "utc",
+			value: "20200601T100000Z",
+			want:  time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "tzid",
+			value:  "20200601T100000",
+			params: map[string]string{"TZID": "Europe/London"},
+			want:   time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+			tzid:   "Europe/London",
+		},
+		{
+			name:   "windows tzid",
+			value:  "20200601T100000",
+			params: map[string]string{"TZID": "Pacific Standard Time"},
+			want:   time.Date(2020, 6, 1, 17, 0, 0, 0, time.UTC),
+			tzid:   "Pacific Standard Time",
+		},
+		{
+			name:   "tzid of the calendar",
+			value:  "20200601T100000",
+			params: map[string]string{"TZID": "Custom"},
+			zones:  map[string]*time.Location{"Custom": time.FixedZone("Custom", 2*3600)},
+			want:   time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+			tzid:   "Custom",
+		},
+		{
+			name:    "unknown tzid",
+			value:   "20200601T100000",
+			params:  map[string]string{"TZID": "Mars Standard Time"},
+			wantErr: true,
+		},
+		{
+			name:  "floating is local time",
+			value: "20200601T100000",
+			want:  time.Date(2020, 6, 1, 10, 0, 0, 0, time.Local),
+		},
+		{
+			name:   "date",
+			value:  "20200601",
+			params: map[string]string{"VALUE": "DATE"},
+			want:   time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local),
+			allDay: true,
+		},
+		{
+			name:   "tzid of date in winter",
+			value:  "20200101T100000",
+			params: map[string]string{"TZID": "Europe/London"},
+			want:   time.Date(2020, 1, 1, 10, 0, 0, 0, london),
+			tzid:   "Europe/London",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			if params == nil {
+				params = map[string]string{}
+			}
+			got, err := parseICalTime(tt.value, params, tt.zones)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseICalTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.t.Equal(tt.want) || got.allDay != tt.allDay || got.tzid != tt.tzid {
+				t.Errorf("parseICalTime() = %v %v %q, want %v %v %q", got.t, got.allDay, got.tzid, tt.want, tt.allDay, tt.tzid)
+			}
+		})
+	}
+}
+
+func TestExpandICalEvents(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/London"); err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+	min := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	max := time.Date(2020, 6, 22, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		body string
+		// id and start of each occurrence
+		want [][2]string
+	}{
+		{
+			name: "single event in the window",
+			body: `BEGIN:VEVENT
+UID:a
+DTSTART:20200602T100000Z
+DTEND:20200602T110000Z
+END:VEVENT`,
+			want: [][2]string{{"a", "2020-06-02T10:00:00Z"}},
+		},
+		{
+			name: "single event out of the window",
+			body: `BEGIN:VEVENT
+UID:a
+DTSTART:20200701T100000Z
+END:VEVENT`,
+			want: [][2]string{},
+		},
+		{
+			name: "event that started before the window and ends in it",
+			body: `BEGIN:VEVENT
+UID:a
+DTSTART:20200531T230000Z
+DURATION:PT2H
+END:VEVENT`,
+			want: [][2]string{{"a", "2020-05-31T23:00:00Z"}},
+		},
+		{
+			name: "weekly rule",
+			body: `BEGIN:VEVENT
+UID:w
+DTSTART:20200525T100000Z
+DTEND:20200525T110000Z
+RRULE:FREQ=WEEKLY
+END:VEVENT`,
+			want: [][2]string{
+				{"w_20200601T100000Z", "2020-06-01T10:00:00Z"},
+				{"w_20200608T100000Z", "2020-06-08T10:00:00Z"},
+				{"w_20200615T100000Z", "2020-06-15T10:00:00Z"},
+			},
+		},
+		{
+			name: "weekly rule in tzid keeps the local time",
+			body: `BEGIN:VEVENT
+UID:w
+DTSTART;TZID=Europe/London:20200525T100000
+DTEND;TZID=Europe/London:20200525T110000
+RRULE:FREQ=WEEKLY;COUNT=3
+END:VEVENT`,
+			want: [][2]string{
+				{"w_20200601T090000Z", "2020-06-01T10:00:00+01:00"},
+				{"w_20200608T090000Z", "2020-06-08T10:00:00+01:00"},
+			},
+		},
+		{
+			name: "windows tzid of outlook",
+			body: `BEGIN:VEVENT
+UID:o
+DTSTART;TZID=W. Europe Standard Time:20200602T100000
+END:VEVENT`,
+			want: [][2]string{{"o", "2020-06-02T10:00:00+02:00"}},
+		},
+		{
+			name: "tzid of vtimezone with location",
+			body: `BEGIN:VTIMEZONE
+TZID:London Office
+X-LIC-LOCATION:Europe/London
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:l
+DTSTART;TZID=London Office:20200602T100000
+END:VEVENT`,
+			want: [][2]string{{"l", "2020-06-02T10:00:00+01:00"}},
+		},
+		{
+			name: "tzid of vtimezone with standard offset",
+			body: `BEGIN:VTIMEZONE
+TZID:(UTC+02:00) Custom
+BEGIN:DAYLIGHT
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0300
+END:DAYLIGHT
+BEGIN:STANDARD
+TZOFFSETFROM:+0300
+TZOFFSETTO:+0200
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:c
+DTSTART;TZID="(UTC+02:00) Custom":20200602T100000
+END:VEVENT`,
+			want: [][2]string{{"c", "2020-06-02T10:00:00+02:00"}},
+		},
+		{
+			name: "exdate",
+			body: `BEGIN:VEVENT
+UID:w
+DTSTART:20200525T100000Z
+DTEND:20200525T110000Z
+RRULE:FREQ=WEEKLY
+EXDATE:20200601T100000Z,20200615T100000Z
+END:VEVENT`,
+			want: [][2]string{{"w_20200608T100000Z", "2020-06-08T10:00:00Z"}},
+		},
+		{
+			name: "recurrence-id replaces the occurrence",
+			body: `BEGIN:VEVENT
+UID:w
+DTSTART:20200525T100000Z
+DTEND:20200525T110000Z
+RRULE:FREQ=WEEKLY;UNTIL=20200610T000000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:w
+RECURRENCE-ID:20200601T100000Z
+DTSTART:20200602T150000Z
+DTEND:20200602T160000Z
+END:VEVENT`,
+			want: [][2]string{
+				{"w_20200601T100000Z", "2020-06-02T15:00:00Z"},
+				{"w_20200608T100000Z", "2020-06-08T10:00:00Z"},
+			},
+		},
+		{
+			name: "all day event",
+			body: `BEGIN:VEVENT
+UID:d
+DTSTART;VALUE=DATE:20200610
+END:VEVENT`,
+			want: [][2]string{{"d", "2020-06-10"}},
+		},
+		{
+			name: "nested components are skipped",
+			body: `BEGIN:VEVENT
+UID:a
+DTSTART:20200602T100000Z
+BEGIN:VALARM
+TRIGGER:-PT15M
+DTSTART:20200101T000000Z
+END:VALARM
+END:VEVENT`,
+			want: [][2]string{{"a", "2020-06-02T10:00:00Z"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := parseICal("BEGIN:VCALENDAR\n" + tt.body + "\nEND:VCALENDAR")
+			if err != nil {
+				t.Fatalf("parseICal() error = %v", err)
+			}
+			occurrences, err := expandICalEvents(events, min, max)
+			if err != nil {
+				t.Fatalf("expandICalEvents() error = %v", err)
+			}
+			got := [][2]string{}
+			for _, o := range occurrences {
+				start := o["start"].(map[string]interface{})
+				s, ok := start["dateTime"].(string)
+				if !ok {
+					s, _ = start["date"].(string)
+				}
+				got = append(got, [2]string{o["id"].(string), s})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandICalEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICalCancelledEvent(t *testing.T) {
+	events, err := parseICal(strings.Join([]string{
+		"BEGIN:VEVENT",
+		"UID:a",
+		"STATUS:CANCELLED",
+		"DTSTART:20200602T100000Z",
+		"END:VEVENT",
+	}, "\r\n"))
+	if err != nil {
+		t.Fatalf("parseICal() error = %v", err)
+	}
+	occurrences, err := expandICalEvents(events, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("expandICalEvents() error = %v", err)
+	}
+	if len(occurrences) != 1 || occurrences[0]["status"] != googleCalendarStatusCancelled {
+		t.Errorf("expandICalEvents() = %v, want one cancelled event", occurrences)
+	}
+}
+
+func TestICalCategories(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  interface{}
+	}{
+		{
+			name:  "comma separated",
+			lines: []string{"CATEGORIES:Work,Meeting"},
+			want:  []string{"Work", "Meeting"},
+		},
+		{
+			name:  "escaped comma",
+			lines: []string{`CATEGORIES:Q\,A,R\;D,C:\\temp`},
+			want:  []string{"Q,A", "R;D", `C:\temp`},
+		},
+		{
+			name:  "several properties",
+			lines: []string{"CATEGORIES:Work", "CATEGORIES:Travel"},
+			want:  []string{"Work", "Travel"},
+		},
+		{
+			name: "no categories",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"BEGIN:VEVENT", "UID:a", "DTSTART:20200602T100000Z"}, tt.lines...)
+			events, err := parseICal(strings.Join(append(lines, "END:VEVENT"), "\r\n"))
+			if err != nil {
+				t.Fatalf("parseICal() error = %v", err)
+			}
+			occurrences, err := expandICalEvents(events, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatalf("expandICalEvents() error = %v", err)
+			}
+			if got := occurrences[0]["categories"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("categories = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
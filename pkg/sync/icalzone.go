@@ -0,0 +1,136 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	// icalWindowsZones are the IANA names of the windows time zones Outlook and Exchange use as TZID
+	// see https://github.com/unicode-org/cldr/blob/main/common/supplemental/windowsZones.xml
+	icalWindowsZones = map[string]string{
+		"Dateline Standard Time":          "Etc/GMT+12",
+		"UTC-11":                          "Etc/GMT+11",
+		"Hawaiian Standard Time":          "Pacific/Honolulu",
+		"Alaskan Standard Time":           "America/Anchorage",
+		"Pacific Standard Time (Mexico)":  "America/Tijuana",
+		"Pacific Standard Time":           "America/Los_Angeles",
+		"US Mountain Standard Time":       "America/Phoenix",
+		"Mountain Standard Time (Mexico)": "America/Mazatlan",
+		"Mountain Standard Time":          "America/Denver",
+		"Central America Standard Time":   "America/Guatemala",
+		"Central Standard Time":           "America/Chicago",
+		"Central Standard Time (Mexico)":  "America/Mexico_City",
+		"Canada Central Standard Time":    "America/Regina",
+		"SA Pacific Standard Time":        "America/Bogota",
+		"Eastern Standard Time (Mexico)":  "America/Cancun",
+		"Eastern Standard Time":           "America/New_York",
+		"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+		"Venezuela Standard Time":         "America/Caracas",
+		"Atlantic Standard Time":          "America/Halifax",
+		"SA Western Standard Time":        "America/La_Paz",
+		"Pacific SA Standard Time":        "America/Santiago",
+		"Newfoundland Standard Time":      "America/St_Johns",
+		"E. South America Standard Time":  "America/Sao_Paulo",
+		"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+		"SA Eastern Standard Time":        "America/Cayenne",
+		"Greenland Standard Time":         "America/Godthab",
+		"UTC":                             "Etc/UTC",
+		"GMT Standard Time":               "Europe/London",
+		"Greenwich Standard Time":         "Atlantic/Reykjavik",
+		"W. Europe Standard Time":         "Europe/Berlin",
+		"Central Europe Standard Time":    "Europe/Budapest",
+		"Romance Standard Time":           "Europe/Paris",
+		"Central European Standard Time":  "Europe/Warsaw",
+		"W. Central Africa Standard Time": "Africa/Lagos",
+		"GTB Standard Time":               "Europe/Bucharest",
+		"E. Europe Standard Time":         "Europe/Chisinau",
+		"Egypt Standard Time":             "Africa/Cairo",
+		"FLE Standard Time":               "Europe/Kiev",
+		"Israel Standard Time":            "Asia/Jerusalem",
+		"South Africa Standard Time":      "Africa/Johannesburg",
+		"Turkey Standard Time":            "Europe/Istanbul",
+		"Arabic Standard Time":            "Asia/Baghdad",
+		"Arab Standard Time":              "Asia/Riyadh",
+		"Russian Standard Time":           "Europe/Moscow",
+		"E. Africa Standard Time":         "Africa/Nairobi",
+		"Iran Standard Time":              "Asia/Tehran",
+		"Arabian Standard Time":           "Asia/Dubai",
+		"Afghanistan Standard Time":       "Asia/Kabul",
+		"Pakistan Standard Time":          "Asia/Karachi",
+		"India Standard Time":             "Asia/Kolkata",
+		"Nepal Standard Time":             "Asia/Kathmandu",
+		"Bangladesh Standard Time":        "Asia/Dhaka",
+		"SE Asia Standard Time":           "Asia/Bangkok",
+		"China Standard Time":             "Asia/Shanghai",
+		"Singapore Standard Time":         "Asia/Singapore",
+		"Taipei Standard Time":            "Asia/Taipei",
+		"W. Australia Standard Time":      "Australia/Perth",
+		"Tokyo Standard Time":             "Asia/Tokyo",
+		"Korea Standard Time":             "Asia/Seoul",
+		"Cen. Australia Standard Time":    "Australia/Adelaide",
+		"AUS Central Standard Time":       "Australia/Darwin",
+		"E. Australia Standard Time":      "Australia/Brisbane",
+		"AUS Eastern Standard Time":       "Australia/Sydney",
+		"Tasmania Standard Time":          "Australia/Hobart",
+		"New Zealand Standard Time":       "Pacific/Auckland",
+		"Tonga Standard Time":             "Pacific/Tongatapu",
+	}
+)
+
+// loadICalLocation returns the location of IANA or windows time zone name
+func loadICalLocation(tzid string) (*time.Location, bool) {
+	if l, err := time.LoadLocation(tzid); err == nil {
+		return l, true
+	}
+	if name, ok := icalWindowsZones[tzid]; ok {
+		if l, err := time.LoadLocation(name); err == nil {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// resolve returns the location of the zone by its name, its X-LIC-LOCATION, or the offset of its standard time
+// zones that are known by the offset only do not switch to daylight saving time
+func (z icalZone) resolve() (*time.Location, bool) {
+	if z.tzid == "" {
+		return nil, false
+	}
+	if l, ok := loadICalLocation(z.tzid); ok {
+		return l, true
+	}
+	if z.location != "" {
+		if l, ok := loadICalLocation(z.location); ok {
+			return l, true
+		}
+	}
+	offset, err := parseICalOffset(z.offset)
+	if err != nil {
+		return nil, false
+	}
+	return time.FixedZone(z.tzid, offset), true
+}
+
+// parseICalOffset returns the seconds of UTC offset like +0100, -0430 or +053000
+func parseICalOffset(value string) (int, error) {
+	if (len(value) != 5 && len(value) != 7) || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("Invalid UTC offset \"%s\"", value)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+i*2 >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+i*2 : 3+i*2])
+		if err != nil {
+			return 0, fmt.Errorf("Invalid UTC offset \"%s\"", value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
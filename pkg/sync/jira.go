@@ -0,0 +1,124 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/open-integration/core/pkg/task"
+	"github.com/open-integration/service-catalog/jira/pkg/endpoints/list"
+)
+
+const (
+	// jiraTimeFormat is the format of the dates returned by the JIRA API
+	jiraTimeFormat = "2006-01-02T15:04:05.000-0700"
+)
+
+type (
+	jiraSource struct {
+		src Source
+	}
+
+	jiraListOptions struct {
+		token    string
+		endpoint string
+		user     string
+		jql      string
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindJIRA, func(src Source) (SourceKind, error) {
+		if src.JIRA == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no jira config", src.Name)
+		}
+		return &jiraSource{src: src}, nil
+	})
+}
+
+// Fetch returns the issues that match the jql with "issue" data
+func (s *jiraSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	u, err := buildURL(s.src.JIRA.Endpoint, "", "")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Failed to build URL from %s: %w", s.src.JIRA.Endpoint, err)
+	}
+	res := list.ListReturns{}
+	if err := callService(ctx, req.Caller, req.FD, "jira", "list", jiraListArguments(jiraListOptions{
+		endpoint: u,
+		jql:      template.String(&s.src.JIRA.JQL, nil),
+		token:    template.String(&s.src.JIRA.Token, nil),
+		user:     template.String(&s.src.JIRA.User, nil),
+	}), &res); err != nil {
+		return FetchResult{}, err
+	}
+	// only the first page of the issues is returned, issues of the next pages are not removed
+	result := FetchResult{
+		Partial: len(res.Issues) >= jiraMaxResults,
+	}
+	if res.Total != nil {
+		result.Partial = *res.Total > int64(len(res.Issues))
+	}
+	for _, issue := range res.Issues {
+		result.Items = append(result.Items, Item{
+			Key: jiraIssueKey(issue),
+			Data: map[string]interface{}{
+				"issue": jiraIssueToJSON(issue),
+			},
+			Meta: jiraIssueMeta(issue),
+		})
+	}
+	return result, nil
+}
+
+func jiraListArguments(options jiraListOptions) []task.Argument {
+	return []task.Argument{
+		{
+			Key:   "API_Token",
+			Value: options.token,
+		},
+		{
+			Key:   "Endpoint",
+			Value: options.endpoint,
+		},
+		{
+			Key:   "User",
+			Value: options.user,
+		},
+		{
+			Key:   "JQL",
+			Value: options.jql,
+		},
+		{
+			Key:   "QueryFields",
+			Value: "*all",
+		},
+	}
+}
+
+func jiraIssueMeta(issue list.Issue) ItemMeta {
+	meta := ItemMeta{
+		GUID: jiraIssueKey(issue),
+	}
+	if s, ok := issue.Fields["summary"].(string); ok {
+		meta.Title = s
+	}
+	if s, ok := issue.Fields["created"].(string); ok {
+		if t, err := time.Parse(jiraTimeFormat, s); err == nil {
+			meta.Published = &t
+		}
+	}
+	if labels, ok := issue.Fields["labels"].([]interface{}); ok {
+		for _, l := range labels {
+			meta.Categories = append(meta.Categories, fmt.Sprintf("%v", l))
+		}
+	}
+	if reporter, ok := issue.Fields["reporter"].(map[string]interface{}); ok {
+		for _, k := range []string{"displayName", "emailAddress"} {
+			if s, ok := reporter[k].(string); ok && s != "" {
+				meta.Authors = append(meta.Authors, s)
+			}
+		}
+	}
+	return meta
+}
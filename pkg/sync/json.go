@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+type (
+	jsonSource struct {
+		src Source
+	}
+
+	// jsonFormat describes how the items are read from json document
+	jsonFormat struct {
+		typ       string
+		itemsPath string
+		keyPath   string
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindJSON, func(src Source) (SourceKind, error) {
+		if src.JSON == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no json config", src.Name)
+		}
+		return &jsonSource{src: src}, nil
+	})
+}
+
+// Fetch returns the items of the response, see jsonItems
+// the item has "content" data
+func (s *jsonSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	u, err := buildURL(s.src.JSON.URL, "", "")
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Failed to build URL from %s: %w", s.src.JSON.URL, err)
+	}
+	res, st, err := fetchURL(ctx, req, u, conditionalGetEnabled(s.src))
+	if err != nil {
+		return FetchResult{}, err
+	}
+	result := FetchResult{
+		Status: res.Status,
+		State:  st,
+	}
+	if err := readHTTPResponse(res); err != nil {
+		return result, err
+	}
+	if notModified(res) {
+		result.NotModified = true
+		return result, nil
+	}
+	items, err := jsonItems(res.Body, jsonFormat{
+		typ:       s.src.JSON.Type,
+		itemsPath: s.src.JSON.ItemsPath,
+		keyPath:   s.src.JSON.KeyPath,
+	}, nil)
+	if err != nil {
+		return result, err
+	}
+	result.Items = items
+	return result, nil
+}
+
+// jsonItems returns the body as single item or item per element when the type is array
+// when the format has items path the items are the elements it selects
+// the extra data is added to each of the items
+func jsonItems(body string, format jsonFormat, extra map[string]interface{}) ([]Item, error) {
+	contents := []map[string]interface{}{}
+	if format.itemsPath != "" {
+		selected, err := selectJSONItems(body, format.itemsPath)
+		if err != nil {
+			return nil, err
+		}
+		contents = selected
+	} else if format.typ == "array" {
+		contents = toArrayJSON([]byte(body))
+	} else {
+		contents = append(contents, toJSON([]byte(body)))
+	}
+	var keyPath *gojq.Code
+	if format.keyPath != "" {
+		code, err := compileJQ(format.keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse key-path: %w", err)
+		}
+		keyPath = code
+	}
+	items := []Item{}
+	for _, c := range contents {
+		data := map[string]interface{}{
+			"content": c,
+		}
+		for k, v := range extra {
+			data[k] = v
+		}
+		key := jsonContentKey(c)
+		if keyPath != nil {
+			k, err := selectJSONKey(c, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			if k != "" {
+				key = k
+			}
+		}
+		items = append(items, Item{
+			Key:  key,
+			Data: data,
+		})
+	}
+	return items, nil
+}
+
+// selectJSONItems returns the objects the jq query selects in the body
+// an array that is selected as a whole is iterated, e.g. .data.items is the same as .data.items[]
+func selectJSONItems(body string, query string) ([]map[string]interface{}, error) {
+	var in interface{}
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		return nil, fmt.Errorf("Failed to parse response: %w", err)
+	}
+	code, err := compileJQ(query)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse items-path: %w", err)
+	}
+	values, err := runJQ(code, in)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to run items-path: %w", err)
+	}
+	if len(values) == 1 {
+		if arr, ok := values[0].([]interface{}); ok {
+			values = arr
+		}
+	}
+	items := []map[string]interface{}{}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items-path selected %T, expected object", v)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// selectJSONKey returns the first value the jq query selects in the item, empty when there is none
+func selectJSONKey(item map[string]interface{}, code *gojq.Code) (string, error) {
+	values, err := runJQ(code, item)
+	if err != nil {
+		return "", fmt.Errorf("Failed to run key-path: %w", err)
+	}
+	if len(values) == 0 || values[0] == nil {
+		return "", nil
+	}
+	if s, ok := values[0].(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(values[0])
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// runJQ returns all the values the query outputs
+func runJQ(code *gojq.Code, in interface{}) ([]interface{}, error) {
+	values := []interface{}{}
+	iter := code.Run(in)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return values, nil
+		}
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+func compileJQ(query string) (*gojq.Code, error) {
+	q, err := gojq.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return gojq.Compile(q)
+}
@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectJSONItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		query   string
+		want    []map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "array selected as a whole is iterated",
+			body:  `{"data":{"items":[{"id":1},{"id":2}]}}`,
+			query: ".data.items",
+			want: []map[string]interface{}{
+				{"id": float64(1)},
+				{"id": float64(2)},
+			},
+		},
+		{
+			name:  "iterated array",
+			body:  `{"data":{"items":[{"id":1},{"id":2}]}}`,
+			query: ".data.items[]",
+			want: []map[string]interface{}{
+				{"id": float64(1)},
+				{"id": float64(2)},
+			},
+		},
+		{
+			name:  "root array",
+			body:  `[{"id":"a"}]`,
+			query: ".",
+			want: []map[string]interface{}{
+				{"id": "a"},
+			},
+		},
+		{
+			name:  "single object",
+			body:  `{"item":{"id":"a"}}`,
+			query: ".item",
+			want: []map[string]interface{}{
+				{"id": "a"},
+			},
+		},
+		{
+			name:  "select filters the items",
+			body:  `{"items":[{"id":1,"open":true},{"id":2,"open":false}]}`,
+			query: ".items[] | select(.open)",
+			want: []map[string]interface{}{
+				{"id": float64(1), "open": true},
+			},
+		},
+		{
+			name:  "null values are skipped",
+			body:  `{"items":[{"id":1},null]}`,
+			query: ".items",
+			want: []map[string]interface{}{
+				{"id": float64(1)},
+			},
+		},
+		{
+			name:  "missing path selects nothing",
+			body:  `{"items":[]}`,
+			query: ".data.items",
+			want:  []map[string]interface{}{},
+		},
+		{
+			name:    "value that is not an object",
+			body:    `{"items":[1,2]}`,
+			query:   ".items",
+			wantErr: true,
+		},
+		{
+			name:    "invalid body",
+			body:    `<html>`,
+			query:   ".",
+			wantErr: true,
+		},
+		{
+			name:    "invalid query",
+			body:    `{}`,
+			query:   ".items[",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectJSONItems(tt.body, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectJSONItems() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectJSONItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
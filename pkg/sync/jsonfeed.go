@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	feedTypeJSON = "json"
+
+	// jsonFeedVersionPrefix is the prefix of the version of all the versions of json feed
+	jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+)
+
+type (
+	// jsonFeed is json feed version 1 and 1.1, see https://jsonfeed.org/version/1.1
+	jsonFeed struct {
+		Version     string           `json:"version"`
+		Title       string           `json:"title"`
+		HomePageURL string           `json:"home_page_url"`
+		FeedURL     string           `json:"feed_url"`
+		Description string           `json:"description"`
+		Icon        string           `json:"icon"`
+		Language    string           `json:"language"`
+		Author      *jsonFeedAuthor  `json:"author"`
+		Authors     []jsonFeedAuthor `json:"authors"`
+		Items       []jsonFeedItem   `json:"items"`
+	}
+
+	jsonFeedItem struct {
+		// ID is a string by the spec, some feeds use numbers
+		ID            interface{}          `json:"id"`
+		URL           string               `json:"url"`
+		Title         string               `json:"title"`
+		ContentHTML   string               `json:"content_html"`
+		ContentText   string               `json:"content_text"`
+		Summary       string               `json:"summary"`
+		Image         string               `json:"image"`
+		DatePublished string               `json:"date_published"`
+		DateModified  string               `json:"date_modified"`
+		Author        *jsonFeedAuthor      `json:"author"`
+		Authors       []jsonFeedAuthor     `json:"authors"`
+		Tags          []string             `json:"tags"`
+		Attachments   []jsonFeedAttachment `json:"attachments"`
+	}
+
+	jsonFeedAuthor struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	jsonFeedAttachment struct {
+		URL               string  `json:"url"`
+		MimeType          string  `json:"mime_type"`
+		Title             string  `json:"title"`
+		SizeInBytes       int64   `json:"size_in_bytes"`
+		DurationInSeconds float64 `json:"duration_in_seconds"`
+	}
+)
+
+// parseFeed parses rss, atom and json feed, the attachments of the items of json feed are returned by item index
+// json feed is translated to the universal feed of gofeed, that parses only rss and atom
+func parseFeed(body string) (*gofeed.Feed, [][]jsonFeedAttachment, error) {
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		feed, err := gofeed.NewParser().ParseString(body)
+		return feed, nil, err
+	}
+	f := jsonFeed{}
+	if err := json.Unmarshal([]byte(body), &f); err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse json feed: %w", err)
+	}
+	if !strings.HasPrefix(f.Version, jsonFeedVersionPrefix) {
+		return nil, nil, fmt.Errorf("Failed to detect feed type, json must be json feed")
+	}
+	feed := &gofeed.Feed{
+		Title:       f.Title,
+		Description: f.Description,
+		Link:        f.HomePageURL,
+		FeedLink:    f.FeedURL,
+		Language:    f.Language,
+		Author:      jsonFeedPerson(f.Author, f.Authors),
+		FeedType:    feedTypeJSON,
+		FeedVersion: strings.TrimPrefix(f.Version, jsonFeedVersionPrefix),
+		Items:       []*gofeed.Item{},
+	}
+	if f.Icon != "" {
+		feed.Image = &gofeed.Image{
+			URL: f.Icon,
+		}
+	}
+	attachments := [][]jsonFeedAttachment{}
+	for _, i := range f.Items {
+		item := &gofeed.Item{
+			Title:       i.Title,
+			Description: i.Summary,
+			Content:     i.ContentHTML,
+			Link:        i.URL,
+			Published:   i.DatePublished,
+			Updated:     i.DateModified,
+			Author:      jsonFeedPerson(i.Author, i.Authors),
+			Categories:  i.Tags,
+		}
+		if item.Content == "" {
+			item.Content = i.ContentText
+		}
+		if i.ID != nil {
+			item.GUID = fmt.Sprintf("%v", i.ID)
+		}
+		if i.Image != "" {
+			item.Image = &gofeed.Image{
+				URL: i.Image,
+			}
+		}
+		if t, err := time.Parse(time.RFC3339, i.DatePublished); err == nil {
+			item.PublishedParsed = &t
+		}
+		if t, err := time.Parse(time.RFC3339, i.DateModified); err == nil {
+			item.UpdatedParsed = &t
+		}
+		for _, a := range i.Attachments {
+			item.Enclosures = append(item.Enclosures, &gofeed.Enclosure{
+				URL:    a.URL,
+				Type:   a.MimeType,
+				Length: strconv.FormatInt(a.SizeInBytes, 10),
+			})
+		}
+		feed.Items = append(feed.Items, item)
+		attachments = append(attachments, append([]jsonFeedAttachment{}, i.Attachments...))
+	}
+	return feed, attachments, nil
+}
+
+// jsonFeedPerson returns the author of version 1 or the first of the authors of version 1.1
+func jsonFeedPerson(author *jsonFeedAuthor, authors []jsonFeedAuthor) *gofeed.Person {
+	if author == nil && len(authors) > 0 {
+		author = &authors[0]
+	}
+	if author == nil {
+		return nil
+	}
+	return &gofeed.Person{
+		Name: author.Name,
+	}
+}
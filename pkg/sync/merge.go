@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fetchSources fetches the sources of binding with sources one after the other
+// and returns their items merged into one result
+func fetchSources(ctx context.Context, srcs []Source, kinds []SourceKind, req FetchRequest) (FetchResult, error) {
+	result := FetchResult{}
+	items := []Item{}
+	for i, kind := range kinds {
+		res, err := kind.Fetch(ctx, req)
+		if res.Status > result.Status {
+			result.Status = res.Status
+		}
+		result.Partial = result.Partial || res.Partial
+		if err != nil {
+			return result, fmt.Errorf("Failed to fetch source \"%s\": %w", srcs[i].Name, err)
+		}
+		for _, item := range res.Items {
+			item.source = srcs[i].Name
+			items = append(items, item)
+		}
+	}
+	result.Items = mergeItems(items)
+	return result, nil
+}
+
+// mergeItems sorts the items by date, oldest first, and drops the copies of items of other sources
+// items are copies when they have the same guid, link or normalized title
+// items without date are kept at the end in the order of the sources
+func mergeItems(items []Item) []Item {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Meta.Published, items[j].Meta.Published
+		if a == nil {
+			return false
+		}
+		if b == nil {
+			return true
+		}
+		return a.Before(*b)
+	})
+	// source of the first item seen by each identity
+	seen := map[string]string{}
+	merged := []Item{}
+	for _, item := range items {
+		duplicate := false
+		ids := itemIdentities(item.Meta)
+		for _, id := range ids {
+			if src, ok := seen[id]; ok && src != item.source {
+				duplicate = true
+			}
+		}
+		for _, id := range ids {
+			if _, ok := seen[id]; !ok {
+				seen[id] = item.source
+			}
+		}
+		if !duplicate {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+func itemIdentities(meta ItemMeta) []string {
+	ids := []string{}
+	if meta.GUID != "" {
+		ids = append(ids, "guid"+seperator+meta.GUID)
+	}
+	if meta.Link != "" {
+		ids = append(ids, "link"+seperator+meta.Link)
+	}
+	if t := normalizeTitle(meta.Title); t != "" {
+		ids = append(ids, "title"+seperator+t)
+	}
+	return ids
+}
+
+// normalizeTitle lower cases the title and keeps only its words
+// so "Episode 12: Go!" and "episode 12 - go" are the same
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
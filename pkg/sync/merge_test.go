@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeItems(t *testing.T) {
+	at := func(day int) *time.Time {
+		t := time.Date(2020, 6, day, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	tests := []struct {
+		name  string
+		items []Item
+		// keys of the merged items
+		want []string
+	}{
+		{
+			name: "sorted by date, oldest first",
+			items: []Item{
+				{Key: "b", source: "s1", Meta: ItemMeta{GUID: "b", Published: at(2)}},
+				{Key: "c", source: "s2", Meta: ItemMeta{GUID: "c", Published: at(3)}},
+				{Key: "a", source: "s2", Meta: ItemMeta{GUID: "a", Published: at(1)}},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "items without date are kept at the end in the order of the sources",
+			items: []Item{
+				{Key: "x", source: "s1", Meta: ItemMeta{GUID: "x"}},
+				{Key: "y", source: "s2", Meta: ItemMeta{GUID: "y"}},
+				{Key: "a", source: "s2", Meta: ItemMeta{GUID: "a", Published: at(1)}},
+			},
+			want: []string{"a", "x", "y"},
+		},
+		{
+			name: "same guid in other source is dropped, the oldest is kept",
+			items: []Item{
+				{Key: "s1-a", source: "s1", Meta: ItemMeta{GUID: "a", Published: at(2)}},
+				{Key: "s2-a", source: "s2", Meta: ItemMeta{GUID: "a", Published: at(1)}},
+			},
+			want: []string{"s2-a"},
+		},
+		{
+			name: "same link in other source is dropped",
+			items: []Item{
+				{Key: "s1-a", source: "s1", Meta: ItemMeta{GUID: "1", Link: "http://x/a"}},
+				{Key: "s2-a", source: "s2", Meta: ItemMeta{GUID: "2", Link: "http://x/a"}},
+			},
+			want: []string{"s1-a"},
+		},
+		{
+			name: "same normalized title in other source is dropped",
+			items: []Item{
+				{Key: "s1-a", source: "s1", Meta: ItemMeta{Title: "Episode 12: Go!"}},
+				{Key: "s2-a", source: "s2", Meta: ItemMeta{Title: "episode 12 - go"}},
+			},
+			want: []string{"s1-a"},
+		},
+		{
+			name: "same title in the same source is kept",
+			items: []Item{
+				{Key: "a", source: "s1", Meta: ItemMeta{GUID: "1", Title: "Weekly update"}},
+				{Key: "b", source: "s1", Meta: ItemMeta{GUID: "2", Title: "Weekly update"}},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "items without identity are kept",
+			items: []Item{
+				{Key: "a", source: "s1"},
+				{Key: "b", source: "s2"},
+			},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, item := range mergeItems(tt.items) {
+				got = append(got, item.Key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Episode 12: Go!", want: "episode 12 go"},
+		{title: "  episode 12 - go ", want: "episode 12 go"},
+		{title: "Über café", want: "über café"},
+		{title: "?!", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := normalizeTitle(tt.title); got != tt.want {
+				t.Errorf("normalizeTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/open-integration/core/pkg/state"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "rss_sync"
+)
+
+var (
+	bindingLabels = []string{"file", "binding"}
+
+	metricFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "fetch_duration_seconds",
+		Help:      "Duration of fetching the source of the binding",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, bindingLabels)
+	metricFetchStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "fetch_http_status",
+		Help:      "HTTP status of the last fetch of the source of the binding, 0 when the source is not fetched over HTTP",
+	}, bindingLabels)
+	metricItemsFetched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_fetched_total",
+		Help:      "Items returned by the source of the binding",
+	}, bindingLabels)
+	metricItemsMatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_matched_total",
+		Help:      "Items that passed the filters of the source of the binding",
+	}, bindingLabels)
+	metricItemsSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_skipped_total",
+		Help:      "Items that were skipped as already delivered",
+	}, bindingLabels)
+	metricItemsDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_delivered_total",
+		Help:      "Items that were delivered to the target of the binding",
+	}, bindingLabels)
+	metricItemsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_failed_total",
+		Help:      "Items that failed to be delivered to the target of the binding",
+	}, bindingLabels)
+	metricItemsRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "items_removed_total",
+		Help:      "Cards of items removed from the source the on-removed action was applied on",
+	}, bindingLabels)
+	metricTemplateErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "template_errors_total",
+		Help:      "Filters and templates of targets of the binding that failed to execute",
+	}, bindingLabels)
+	metricBindingErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "binding_errors_total",
+		Help:      "Errors that failed the binding",
+	}, bindingLabels)
+	metricLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "binding_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of the binding",
+	}, bindingLabels)
+)
+
+// Collectors returns the metrics collected while running the syncs
+// they are not registered, register them on the registry the metrics are served from
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		metricFetchDuration,
+		metricFetchStatus,
+		metricItemsFetched,
+		metricItemsMatched,
+		metricItemsSkipped,
+		metricItemsDelivered,
+		metricItemsFailed,
+		metricItemsRemoved,
+		metricTemplateErrors,
+		metricBindingErrors,
+		metricLastSuccess,
+	}
+}
+
+// observeFetch records the duration and the HTTP status of the task that fetched the source of the binding
+func observeFetch(file string, binding string, t state.TaskState, status int) {
+	if !t.Times.Started.IsZero() && !t.Times.Finished.IsZero() {
+		metricFetchDuration.WithLabelValues(file, binding).Observe(t.Times.Finished.Sub(t.Times.Started).Seconds())
+	}
+	metricFetchStatus.WithLabelValues(file, binding).Set(float64(status))
+}
+
+func observeSuccess(file string, binding string) {
+	metricLastSuccess.WithLabelValues(file, binding).Set(float64(time.Now().Unix()))
+}
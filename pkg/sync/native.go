@@ -0,0 +1,285 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/open-integration/service-catalog/google-calendar/pkg/endpoints/getEvents"
+	"github.com/open-integration/service-catalog/http/pkg/endpoints/call"
+	"github.com/open-integration/service-catalog/jira/pkg/endpoints/list"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// EngineOpenIntegration runs the services as processes of the open-integration engine
+	EngineOpenIntegration = "open-integration"
+	// EngineNative calls the APIs from the process using net/http
+	EngineNative = "native"
+
+	googleCalendarAPI = "https://www.googleapis.com/calendar/v3"
+
+	// jiraMaxResults is the page size of the search, only the first page is returned like the jira service does
+	jiraMaxResults = 100
+)
+
+type (
+	// nativeCaller implements the endpoints of the services used by the sources and targets in-process
+	// the arguments and the response are the same as of the service
+	nativeCaller struct {
+		client *http.Client
+	}
+
+	nativeEndpoint func(ctx context.Context, client *http.Client, arguments []byte) (interface{}, error)
+
+	trelloAddCardRequest struct {
+		App         string   `json:"App"`
+		Token       string   `json:"Token"`
+		List        string   `json:"List"`
+		Name        string   `json:"Name"`
+		Description string   `json:"Description"`
+		Labels      []string `json:"Labels"`
+	}
+)
+
+var (
+	nativeEndpoints = map[string]nativeEndpoint{
+		"http/call":                 nativeHTTPCall,
+		"trello/addcard":            nativeTrelloAddCard,
+		"jira/list":                 nativeJIRAList,
+		"google-calendar/getEvents": nativeGoogleCalendarGetEvents,
+	}
+)
+
+// Engines returns the names of the supported engines
+func Engines() []string {
+	return []string{EngineOpenIntegration, EngineNative}
+}
+
+func (n *nativeCaller) Call(ctx context.Context, service string, endpoint string, arguments map[string]interface{}, fd string) ([]byte, error) {
+	fn, ok := nativeEndpoints[fmt.Sprintf("%s/%s", service, endpoint)]
+	if !ok {
+		return nil, fmt.Errorf("Endpoint %s of service %s is not supported by the native engine", endpoint, service)
+	}
+	// the arguments are passed the same way they are sent to the service
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, err
+	}
+	res, err := fn(ctx, n.client, args)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(res)
+}
+
+func nativeHTTPCall(ctx context.Context, client *http.Client, arguments []byte) (interface{}, error) {
+	args, err := call.UnmarshalCallArguments(arguments)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if args.Content != nil {
+		body = strings.NewReader(*args.Content)
+	}
+	req, err := http.NewRequestWithContext(ctx, args.Verb, args.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range args.Headers {
+		if h.Name != nil && h.Value != nil {
+			req.Header.Set(*h.Name, *h.Value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	headers := []call.Header{}
+	for name, values := range resp.Header {
+		name := name
+		value := values[0]
+		headers = append(headers, call.Header{
+			Name:  &name,
+			Value: &value,
+		})
+	}
+	return call.CallReturns{
+		Status:  resp.StatusCode,
+		Headers: headers,
+		Body:    string(b),
+	}, nil
+}
+
+func nativeTrelloAddCard(ctx context.Context, client *http.Client, arguments []byte) (interface{}, error) {
+	args := trelloAddCardRequest{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("key", args.App)
+	q.Set("token", args.Token)
+	b, err := json.Marshal(map[string]interface{}{
+		"idList":   args.List,
+		"name":     args.Name,
+		"desc":     args.Description,
+		"idLabels": strings.Join(args.Labels, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/cards?%s", trelloAPI, q.Encode()), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	card := map[string]interface{}{}
+	if err := doJSON(client, req, &card); err != nil {
+		return nil, fmt.Errorf("Failed to add card: %w", err)
+	}
+	return card, nil
+}
+
+func nativeJIRAList(ctx context.Context, client *http.Client, arguments []byte) (interface{}, error) {
+	args := list.ListArguments{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	// same request the jira service sends
+	search := map[string]interface{}{
+		"startAt":    0,
+		"maxResults": jiraMaxResults,
+	}
+	fields := []string{"summary"}
+	if args.QueryFields != nil && *args.QueryFields != "" {
+		fields = append(fields, strings.Split(*args.QueryFields, ",")...)
+	}
+	search["fields"] = fields
+	jql := "resolution = unresolved"
+	if args.Jql != nil && *args.Jql != "" {
+		jql = *args.Jql
+	}
+	if args.Sort != nil && *args.Sort != "" && (args.Jql == nil || *args.Jql == "") {
+		jql = fmt.Sprintf("%s ORDER BY %s", jql, *args.Sort)
+	}
+	search["jql"] = jql
+	b, err := json.Marshal(search)
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/rest/api/2/search", strings.TrimSuffix(args.Endpoint, "/"))
+	req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", args.User, args.APIToken)))))
+	res := list.ListReturns{}
+	if err := doJSON(client, req, &res); err != nil {
+		return nil, fmt.Errorf("Failed to run search query %w", err)
+	}
+	return res, nil
+}
+
+func nativeGoogleCalendarGetEvents(ctx context.Context, client *http.Client, arguments []byte) (interface{}, error) {
+	args := getEvents.GetEventsArguments{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	sa, err := json.Marshal(args.ServiceAccount)
+	if err != nil {
+		return nil, err
+	}
+	config, err := google.JWTConfigFromJSON(sa, "https://www.googleapis.com/auth/calendar", "https://www.googleapis.com/auth/calendar.events.readonly")
+	if err != nil {
+		return nil, err
+	}
+	authorized := config.Client(context.WithValue(ctx, oauth2.HTTPClient, client))
+	authorized.Timeout = client.Timeout
+
+	q := url.Values{}
+	setString := func(k string, v *string) {
+		if v != nil {
+			q.Set(k, *v)
+		}
+	}
+	setBool := func(k string, v *bool) {
+		if v != nil {
+			q.Set(k, strconv.FormatBool(*v))
+		}
+	}
+	setInt := func(k string, v *int64) {
+		if v != nil {
+			q.Set(k, strconv.FormatInt(*v, 10))
+		}
+	}
+	setString("iCalUID", args.ICalUID)
+	setInt("maxAttendees", args.MaxAttendees)
+	setInt("maxResults", args.MaxResults)
+	if args.OrderBy != nil {
+		q.Set("orderBy", string(*args.OrderBy))
+	}
+	setString("pageToken", args.PageToken)
+	setString("privateExtendedProperty", args.PrivateExtendedProperty)
+	setString("q", args.Q)
+	setString("sharedExtendedProperty", args.SharedExtendedProperty)
+	setBool("showDeleted", args.ShowDeleted)
+	setBool("showHiddenInvitations", args.ShowHiddenInvitations)
+	setBool("singleEvents", args.SingleEvents)
+	setString("timeMax", args.TimeMax)
+	setString("timeMin", args.TimeMin)
+	setString("timeZone", args.TimeZone)
+	setString("updatedMin", args.UpdatedMin)
+
+	u := fmt.Sprintf("%s/calendars/%s/events?%s", googleCalendarAPI, url.PathEscape(args.CalendarID), q.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := struct {
+		Items []getEvents.Event `json:"items"`
+	}{}
+	if err := doJSON(authorized, req, &res); err != nil {
+		return nil, err
+	}
+	events := res.Items
+	if events == nil {
+		events = []getEvents.Event{}
+	}
+	return getEvents.GetEventsReturns{
+		Events: events,
+	}, nil
+}
+
+// doJSON sends the request and unmarshals the response into out
+// unsuccessful response status is returned as error
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Request returned status %d: %s", resp.StatusCode, b)
+	}
+	return json.Unmarshal(b, out)
+}
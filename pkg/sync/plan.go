@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"encoding/json"
+	gosync "sync"
+)
+
+const (
+	actionCreate  = "create"
+	actionUpdate  = "update"
+	actionArchive = "archive"
+	actionMove    = "move"
+	actionComment = "comment"
+)
+
+type (
+	// planner collects the items that would be delivered on dry run
+	planner struct {
+		mux   gosync.Mutex
+		items []PlannedItem
+	}
+
+	// PlannedItem is what the delivery of one item would do
+	PlannedItem struct {
+		Binding string
+		Source  string
+		Target  string
+		// Type is the type of the target, the output is printed under it
+		Type string
+		Key  string
+		Planned
+	}
+)
+
+func (p *planner) add(tc taskCandidate, key string, planned Planned) {
+	t, _ := targetType(tc.target)
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.items = append(p.items, PlannedItem{
+		Binding: tc.binding.Name,
+		Source:  tc.src.Name,
+		Target:  tc.target.Name,
+		Type:    t,
+		Key:     key,
+		Planned: planned,
+	})
+}
+
+func (p *planner) list() []PlannedItem {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]PlannedItem{}, p.items...)
+}
+
+func (c PlannedItem) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"binding": c.Binding,
+		"source":  c.Source,
+		"target":  c.Target,
+		"key":     c.Key,
+		"action":  c.Action,
+	}
+	if c.Output != nil {
+		m[c.Type] = c.Output
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,200 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	gosync "sync"
+
+	"github.com/open-integration/core/pkg/state"
+)
+
+type (
+	// Report is the result of one run of a sync
+	Report struct {
+		// Name is the name of the sync, see Sync.Name
+		Name     string          `json:"name"`
+		Bindings []BindingReport `json:"bindings"`
+		// Err is set when the run failed as a whole
+		Err error `json:"-"`
+	}
+
+	BindingReport struct {
+		Name      string `json:"name"`
+		Fetched   int    `json:"fetched"`
+		Matched   int    `json:"matched"`
+		Skipped   int    `json:"skipped"`
+		Delivered int    `json:"delivered"`
+		Failed    int    `json:"failed"`
+		Removed   int    `json:"removed"`
+		// TemplateErrors counts filters and templates of targets that failed to execute
+		// the item is skipped when a filter failed, it is counted as failed when a template of the target failed
+		TemplateErrors int      `json:"template-errors"`
+		Errors         []string `json:"errors,omitempty"`
+	}
+
+	// report collects the results of each binding of one sync while it runs
+	report struct {
+		mux      gosync.Mutex
+		name     string
+		logger   Logger
+		err      error
+		bindings []*BindingReport
+	}
+)
+
+func newReport(cnf Sync, logger Logger) *report {
+	r := &report{
+		name:   cnf.Name,
+		logger: logger,
+	}
+	for _, b := range cnf.Bindings {
+		r.bindings = append(r.bindings, &BindingReport{
+			Name: b.Name,
+		})
+	}
+	return r
+}
+
+func (r *report) update(binding string, fn func(b *BindingReport)) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, b := range r.bindings {
+		if b.Name == binding {
+			fn(b)
+			return
+		}
+	}
+	b := &BindingReport{
+		Name: binding,
+	}
+	fn(b)
+	r.bindings = append(r.bindings, b)
+}
+
+// fail marks the binding as failed, the other bindings are not affected
+func (r *report) fail(binding string, err error) {
+	r.logger.Printf("[ERROR] Binding %s: %v\n", binding, err)
+	metricBindingErrors.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.Errors = append(b.Errors, err.Error())
+	})
+}
+
+func (r *report) fetched(binding string, n int) {
+	metricItemsFetched.WithLabelValues(r.name, binding).Add(float64(n))
+	r.update(binding, func(b *BindingReport) {
+		b.Fetched += n
+	})
+}
+
+func (r *report) matched(binding string) {
+	metricItemsMatched.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.Matched++
+	})
+}
+
+func (r *report) skipped(binding string) {
+	metricItemsSkipped.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.Skipped++
+	})
+}
+
+// removed counts the cards of removed items the on-removed action was applied on
+func (r *report) removed(binding string) {
+	metricItemsRemoved.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.Removed++
+	})
+}
+
+func (r *report) delivered(binding string) {
+	metricItemsDelivered.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.Delivered++
+	})
+}
+
+// deliveryFailed marks the binding as failed due to one item that was not delivered
+// failed templates of the target are counted as template errors as well
+func (r *report) deliveryFailed(binding string, key string, err error) {
+	r.logger.Printf("[ERROR] Binding %s: failed to deliver item %s: %v\n", binding, key, err)
+	metricItemsFailed.WithLabelValues(r.name, binding).Inc()
+	var tmplErr *targetTemplateError
+	isTemplateErr := errors.As(err, &tmplErr)
+	if isTemplateErr {
+		metricTemplateErrors.WithLabelValues(r.name, binding).Inc()
+	}
+	r.update(binding, func(b *BindingReport) {
+		b.Failed++
+		if isTemplateErr {
+			b.TemplateErrors++
+		}
+		b.Errors = append(b.Errors, fmt.Sprintf("Failed to deliver item %s: %v", key, err))
+	})
+}
+
+// templateError counts template of the binding that failed to execute on one item
+func (r *report) templateError(binding string, err error) {
+	r.logger.Printf("[WARN] Binding %s: %v\n", binding, err)
+	metricTemplateErrors.WithLabelValues(r.name, binding).Inc()
+	r.update(binding, func(b *BindingReport) {
+		b.TemplateErrors++
+	})
+}
+
+// observeFetch records the duration and the HTTP status of the task that fetched the source of the binding
+func (r *report) observeFetch(binding string, t state.TaskState, status int) {
+	observeFetch(r.name, binding, t, status)
+}
+
+// succeeded returns whether the binding succeeded so far
+func (r *report) succeeded(binding string) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.err != nil {
+		return false
+	}
+	for _, b := range r.bindings {
+		if b.Name == binding {
+			return b.Succeeded()
+		}
+	}
+	return true
+}
+
+// finish updates the last success time of the bindings that succeeded
+func (r *report) finish() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.err != nil {
+		return
+	}
+	for _, b := range r.bindings {
+		if b.Succeeded() {
+			observeSuccess(r.name, b.Name)
+		}
+	}
+}
+
+// snapshot returns copy of the results collected so far
+func (r *report) snapshot() Report {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	res := Report{
+		Name: r.name,
+		Err:  r.err,
+	}
+	for _, b := range r.bindings {
+		c := *b
+		c.Errors = append([]string{}, b.Errors...)
+		res.Bindings = append(res.Bindings, c)
+	}
+	return res
+}
+
+// Succeeded returns true when the binding had no errors and all the items were delivered
+func (b BindingReport) Succeeded() bool {
+	return len(b.Errors) == 0 && b.Failed == 0
+}
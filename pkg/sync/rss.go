@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mmcdole/gofeed"
+)
+
+type (
+	rssSource struct {
+		src Source
+	}
+)
+
+func init() {
+	RegisterSource(sourceKindRSS, func(src Source) (SourceKind, error) {
+		if src.RSS == nil {
+			return nil, fmt.Errorf("Source \"%s\" has no rss config", src.Name)
+		}
+		return &rssSource{src: src}, nil
+	})
+}
+
+// Fetch returns the items of the feed with "item" and "feed" data
+func (s *rssSource) Fetch(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	username, password := "", ""
+	if s.src.RSS.Auth != nil {
+		username = s.src.RSS.Auth.Username
+		password = s.src.RSS.Auth.Password
+	}
+	u, err := buildURL(s.src.RSS.URL, username, password)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("Failed to build URL from %s: %w", s.src.RSS.URL, err)
+	}
+	res, st, err := fetchURL(ctx, req, u, conditionalGetEnabled(s.src))
+	if err != nil {
+		return FetchResult{}, err
+	}
+	result := FetchResult{
+		Status: res.Status,
+		State:  st,
+	}
+	if err := readHTTPResponse(res); err != nil {
+		return result, err
+	}
+	if notModified(res) {
+		result.NotModified = true
+		return result, nil
+	}
+	items, err := feedItems(res.Body, nil)
+	if err != nil {
+		return result, err
+	}
+	result.Items = items
+	return result, nil
+}
+
+// feedItems parses the feed and returns its items with "item" and "feed" data
+// the extra data is added to each of the items
+func feedItems(body string, extra map[string]interface{}) ([]Item, error) {
+	feed, attachments, err := parseFeed(body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse feed: %w", err)
+	}
+	feedValues := feedWithExtensionsToJSON(*feed)
+	items := []Item{}
+	for i, item := range feed.Items {
+		var itemAttachments []jsonFeedAttachment
+		if i < len(attachments) {
+			itemAttachments = attachments[i]
+		}
+		data := map[string]interface{}{
+			"item": feedItemToJSON(*item, itemAttachments),
+			"feed": feedValues,
+		}
+		for k, v := range extra {
+			data[k] = v
+		}
+		items = append(items, Item{
+			Key:  gofeedItemKey(*item),
+			Name: item.Title,
+			Data: data,
+			Meta: gofeedItemMeta(*item),
+		})
+	}
+	return items, nil
+}
+
+func gofeedItemMeta(item gofeed.Item) ItemMeta {
+	meta := ItemMeta{
+		GUID:       item.GUID,
+		Link:       item.Link,
+		Title:      item.Title,
+		Published:  item.PublishedParsed,
+		Categories: item.Categories,
+	}
+	if meta.Published == nil {
+		meta.Published = item.UpdatedParsed
+	}
+	if item.Author != nil {
+		for _, a := range []string{item.Author.Name, item.Author.Email} {
+			if a != "" {
+				meta.Authors = append(meta.Authors, a)
+			}
+		}
+	}
+	return meta
+}
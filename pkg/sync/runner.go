@@ -0,0 +1,366 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/olegsu/rss-sync/pkg/values"
+	"github.com/open-integration/core"
+	"github.com/open-integration/core/pkg/downloader"
+	"github.com/open-integration/core/pkg/event"
+	"github.com/open-integration/core/pkg/logger"
+	"github.com/open-integration/core/pkg/state"
+	"github.com/open-integration/core/pkg/task"
+)
+
+type (
+	// Logger prints the errors and warnings of the bindings
+	Logger interface {
+		Printf(format string, v ...interface{})
+	}
+
+	// Options configures the Runner, all the fields are optional
+	Options struct {
+		// HTTPClient sends the requests made from the process, client with one minute timeout by default
+		HTTPClient *http.Client
+		// Logger prints the errors and warnings of the bindings, discarded by default
+		Logger Logger
+		// EngineLogger is the logger of the open-integration engine, engine default when not set
+		EngineLogger logger.Logger
+		// Store keeps the delivered items between the runs, in memory store by default
+		Store store.Store
+		// Engine is the engine that calls the services, see Engines, EngineOpenIntegration by default
+		Engine string
+		// LogsDirectory is where the engine writes the logs of the tasks and the services to, under "logs"
+		// temporary directory that is removed after the run by default
+		LogsDirectory string
+	}
+
+	// Runner executes syncs
+	Runner struct {
+		opt Options
+	}
+
+	taskCandidate struct {
+		target  Target
+		binding Binding
+		src     Source
+		// sources of binding with sources, src is the first of them
+		sources []Source
+		// filter of the target in the targets of the binding
+		filter map[string]string
+	}
+
+	discardLogger struct{}
+)
+
+// NewRunner creates Runner, defaults are set for the options that are not set
+func NewRunner(opt Options) *Runner {
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = &http.Client{
+			Timeout: time.Minute,
+		}
+	}
+	if opt.Logger == nil {
+		opt.Logger = discardLogger{}
+	}
+	if opt.Store == nil {
+		opt.Store, _ = store.New("")
+	}
+	if opt.Engine == "" {
+		opt.Engine = EngineOpenIntegration
+	}
+	return &Runner{
+		opt: opt,
+	}
+}
+
+// Load reads the sync from the file, the name of the sync is the base name of the file
+func Load(location string) (Sync, error) {
+	cnf, err := readFile(location)
+	if err != nil {
+		return cnf, err
+	}
+	cnf.Name = path.Base(location)
+	return cnf, nil
+}
+
+// Run fetches the sources of all the bindings and delivers the items to the targets
+// failures of single bindings are reported, error is returned when the sync could not run
+func (r *Runner) Run(ctx context.Context, cnf Sync) (Report, error) {
+	return r.run(ctx, cnf, nil)
+}
+
+// Plan fetches and filters the sources like Run does and returns what the delivery of each item would do
+// nothing is delivered and the store is not changed
+func (r *Runner) Plan(ctx context.Context, cnf Sync) ([]PlannedItem, Report, error) {
+	p := &planner{}
+	rep, err := r.run(ctx, cnf, p)
+	return p.list(), rep, err
+}
+
+// run executes the pipeline built from the sync
+// when planner is set the items are recorded instead of being delivered
+// the tasks stop once the context is cancelled and the error of the context is returned
+func (r *Runner) run(ctx context.Context, cnf Sync, p *planner) (Report, error) {
+	rep := newReport(cnf, r.opt.Logger)
+	if err := ctx.Err(); err != nil {
+		rep.err = err
+		return rep.snapshot(), err
+	}
+	var caller ServiceCaller
+	services := []core.Service{}
+	switch r.opt.Engine {
+	case EngineOpenIntegration:
+		services = openIntegrationServices()
+	case EngineNative:
+		// the services are not declared, so nothing is downloaded or started by the engine
+		caller = &nativeCaller{
+			client: r.opt.HTTPClient,
+		}
+	default:
+		err := fmt.Errorf("Unknown engine \"%s\", supported: %s", r.opt.Engine, strings.Join(Engines(), ", "))
+		rep.err = err
+		return rep.snapshot(), err
+	}
+	logs := r.opt.LogsDirectory
+	if logs == "" {
+		dir, err := ioutil.TempDir("", "rss-sync-")
+		if err != nil {
+			rep.err = err
+			return rep.snapshot(), err
+		}
+		defer os.RemoveAll(dir)
+		logs = dir
+	}
+	if err := prepareEngine(logs, services); err != nil {
+		rep.err = err
+		return rep.snapshot(), err
+	}
+	tracker := newDeliveryTracker(ctx, r.opt.Store, p, rep, caller, r.opt.HTTPClient)
+	conditionSourceFetched := &taskFinished{}
+	pipe := core.Pipeline{
+		Metadata: core.PipelineMetadata{
+			Name: "sync",
+		},
+		Spec: core.PipelineSpec{
+			Services: services,
+			Reactions: []core.EventReaction{
+				{
+					Condition: core.ConditionEngineStarted(),
+					Reaction: func(ev event.Event, state state.State) []task.Task {
+						if ctx.Err() != nil {
+							return nil
+						}
+						tasks := []task.Task{}
+						for _, binding := range cnf.Bindings {
+							tcs, err := populateTaskCandidates(binding.Name, cnf)
+							if err != nil {
+								rep.fail(binding.Name, err)
+								continue
+							}
+							kinds, err := buildSourceKinds(tcs[0].sources)
+							if err != nil {
+								rep.fail(binding.Name, err)
+								continue
+							}
+							// one fetch of the source feeds all the targets of the binding
+							name := buildTaskName(binding)
+							conditionSourceFetched.AddTask(name)
+							tasks = append(tasks, buildFetchTask(name, tcs, kinds, tracker))
+						}
+						return tasks
+					},
+				},
+				{
+					Condition: conditionSourceFetched,
+					Reaction:  reactToFetchedSource(cnf, tracker),
+				},
+				{
+					Condition: tracker,
+					Reaction:  tracker.reactToCompletedTask,
+				},
+			},
+		},
+	}
+	e := core.NewEngine(&core.EngineOptions{
+		Pipeline:      pipe,
+		Logger:        r.opt.EngineLogger,
+		LogsDirectory: logs,
+	})
+	if err := e.Run(); err != nil {
+		rep.err = err
+		return rep.snapshot(), err
+	}
+	// items delivered before the cancellation are stored, the sources are fetched again by the next run
+	if err := ctx.Err(); err != nil {
+		rep.err = err
+		return rep.snapshot(), err
+	}
+	tracker.saveSourceStates()
+	rep.finish()
+	return rep.snapshot(), nil
+}
+
+// prepareEngine creates the directories and downloads the services the engine uses
+// the engine exits the process when it fails to do so itself
+func prepareEngine(logs string, services []core.Service) error {
+	for _, dir := range []string{"tasks", "services"} {
+		if err := os.MkdirAll(filepath.Join(logs, "logs", dir), os.ModePerm); err != nil {
+			return fmt.Errorf("Failed to create logs directory: %w", err)
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(home, ".open-integration", "services")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("Failed to create services directory: %w", err)
+	}
+	d := downloader.New(downloader.Options{
+		Store:  dir,
+		Logger: logger.New(&logger.Options{}),
+	})
+	for _, s := range services {
+		if _, err := d.Download(s.Name, s.Version); err != nil {
+			return fmt.Errorf("Failed to download service %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// openIntegrationServices returns the services the sources and the targets call
+func openIntegrationServices() []core.Service {
+	return []core.Service{
+		{
+			As:      "http",
+			Name:    "http",
+			Version: "0.0.2",
+		},
+		{
+			Name:    "trello",
+			Version: "0.10.0",
+			As:      "trello",
+		},
+		{
+			Name:    "jira",
+			Version: "0.3.0",
+			As:      "jira",
+		},
+		{
+			Name:    "google-calendar",
+			Version: "0.0.3",
+			As:      "google-calendar",
+		},
+	}
+}
+
+// Fetch fetches the source once and returns its items without filtering or delivering them
+// the services are called in-process and the state of previous fetches is not used
+func (r *Runner) Fetch(ctx context.Context, cnf Sync, source string) ([]Item, error) {
+	src, err := cnf.FindSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("Source \"%s\" not found", source)
+	}
+	kind, err := buildSourceKind(src)
+	if err != nil {
+		return nil, err
+	}
+	res, err := kind.Fetch(ctx, FetchRequest{
+		Caller:     &nativeCaller{client: r.opt.HTTPClient},
+		HTTPClient: r.opt.HTTPClient,
+		State:      map[string]string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func buildValues(taskCandidate taskCandidate) *values.Values {
+	targetValues := targetToJSON(taskCandidate.target)
+	bindingValues := bindingToJSON(taskCandidate.binding)
+	srcValues := srcToJSON(taskCandidate.src)
+	root := &values.Values{}
+	root.Add("source", srcValues)
+	root.Add("binding", bindingValues)
+	root.Add("target", targetValues)
+	return root
+}
+
+// filterSource returns true when all the filters of the source passed
+// filters that failed to execute are reported and considered as not passed
+func filterSource(taskCandidate taskCandidate, data *values.Values, r *report) bool {
+	return runFilters(taskCandidate.binding.Name, taskCandidate.src.Filter, "", data, r)
+}
+
+// filterTarget returns true when all the filters of the target in the targets of the binding passed
+func filterTarget(taskCandidate taskCandidate, data *values.Values, r *report) bool {
+	return runFilters(taskCandidate.binding.Name, taskCandidate.filter, fmt.Sprintf(" of target \"%s\"", taskCandidate.target.Name), data, r)
+}
+
+// runFilters runs the filters on the data, of is added to the reported errors after the name of the filter
+func runFilters(binding string, filters map[string]string, of string, data *values.Values, r *report) bool {
+	matched := true
+	for _, name := range sortedKeys(filters) {
+		res, err := filter(data, filters[name])
+		if err != nil {
+			r.templateError(binding, fmt.Errorf("Filter \"%s\"%s failed: %w", name, of, err))
+		}
+		if !res {
+			matched = false
+		}
+	}
+	return matched
+}
+
+// populateTaskCandidates returns candidate for each of the targets of the binding
+func populateTaskCandidates(bindingname string, cnf Sync) ([]taskCandidate, error) {
+	binding, err := getBinding(bindingname, cnf.Bindings)
+	if err != nil {
+		return nil, fmt.Errorf("Binding \"%s\" not found", bindingname)
+	}
+	sources := []Source{}
+	for _, name := range binding.SourceNames() {
+		source, err := getSource(name, cnf.Sources)
+		if err != nil {
+			return nil, fmt.Errorf("Source \"%s\" not found", name)
+		}
+		sources = append(sources, source)
+	}
+	tcs := []taskCandidate{}
+	for _, t := range targetsOf(binding) {
+		target, err := getTarget(t.Name, cnf.Targets)
+		if err != nil {
+			return nil, fmt.Errorf("Target \"%s\" not found", t.Name)
+		}
+		tcs = append(tcs, taskCandidate{
+			target:  target,
+			binding: binding,
+			src:     sources[0],
+			sources: sources,
+			filter:  t.Filter,
+		})
+	}
+	return tcs, nil
+}
+
+// bucket returns the bucket the items delivered to the target are stored in
+// each of the targets of binding with targets has its own bucket
+func (tc taskCandidate) bucket() string {
+	if len(tc.binding.Targets) == 0 {
+		return tc.binding.Name
+	}
+	return fmt.Sprintf("%s%s%s", tc.binding.Name, seperator, tc.target.Name)
+}
+
+func (discardLogger) Printf(format string, v ...interface{}) {}
@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type (
+	// schedules is due on the next time of any of its schedules
+	schedules []cron.Schedule
+)
+
+// Schedule returns the schedule of the source
+// cron expression takes precedence over interval, def is used when none is set
+func Schedule(src Source, def time.Duration) (cron.Schedule, error) {
+	if src.Cron != "" {
+		return cron.ParseStandard(src.Cron)
+	}
+	interval := def
+	if src.Interval != "" {
+		d, err := time.ParseDuration(src.Interval)
+		if err != nil {
+			return nil, err
+		}
+		interval = d
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("Interval must be positive")
+	}
+	return cron.Every(interval), nil
+}
+
+// BindingSchedule returns the schedule of the binding, binding with sources runs on the schedule of each of them
+func BindingSchedule(cnf Sync, binding Binding, def time.Duration) (cron.Schedule, error) {
+	res := schedules{}
+	for _, name := range binding.SourceNames() {
+		src, err := cnf.FindSource(name)
+		if err != nil {
+			return nil, fmt.Errorf("Source \"%s\" not found", name)
+		}
+		schedule, err := Schedule(src, def)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to build schedule for source \"%s\": %w", src.Name, err)
+		}
+		res = append(res, schedule)
+	}
+	return res, nil
+}
+
+func (s schedules) Next(t time.Time) time.Time {
+	next := time.Time{}
+	for _, schedule := range s {
+		if n := schedule.Next(t); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
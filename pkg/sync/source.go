@@ -0,0 +1,353 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/open-integration/core/pkg/event"
+	"github.com/open-integration/core/pkg/state"
+	"github.com/open-integration/core/pkg/task"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	sourceKindRSS            = "rss"
+	sourceKindJSON           = "json"
+	sourceKindDirectory      = "directory"
+	sourceKindHTML           = "html"
+	sourceKindJIRA           = "jira"
+	sourceKindGoogleCalendar = "google-calendar"
+	sourceKindICal           = "ical"
+)
+
+type (
+	// ServiceCaller calls endpoint of open-integration service
+	ServiceCaller interface {
+		Call(ctx context.Context, service string, endpoint string, arguments map[string]interface{}, fd string) ([]byte, error)
+	}
+
+	// SourceKind fetches the items of one configured source
+	SourceKind interface {
+		Fetch(ctx context.Context, req FetchRequest) (FetchResult, error)
+	}
+
+	// SourceFactory builds the source kind from the config of the source
+	SourceFactory func(src Source) (SourceKind, error)
+
+	FetchRequest struct {
+		Caller ServiceCaller
+		// HTTPClient is used for requests the source sends from the process
+		HTTPClient *http.Client
+		// FD is the log file of the task that runs the fetch
+		FD string
+		// State is the state returned by the previous fetch of the binding that finished successfully
+		// it is empty once the config of the binding was changed
+		State map[string]string
+	}
+
+	FetchResult struct {
+		Items []Item
+		// Status is the HTTP status of the response, 0 when the source is not fetched over HTTP
+		Status int
+		// NotModified is set when the source was not changed since the previous fetch
+		// items that were delivered before are not reconciled
+		NotModified bool
+		// Partial is set when the items are only some of the items of the source, e.g. the events of the time window
+		// or the first page of the search, items that were delivered before and are missing are not treated as removed
+		Partial bool
+		// State is stored once the binding finished successfully and passed to the next fetch
+		State map[string]string
+	}
+
+	// Item is one entry fetched from the source
+	Item struct {
+		// Key identifies the item in the state store when the source has no key template
+		Key string
+		// Name is used in the name of the task that delivers the item
+		Name string
+		// Data is added to the template data of the item, e.g. "item" and "feed" of rss source
+		Data map[string]interface{}
+		// Meta is checked by the built-in filters of the source, e.g. max-age
+		Meta ItemMeta
+		// Removed is set for items the source reports as deleted, e.g. cancelled events
+		// they are not delivered, the on-removed action of the binding is applied on them
+		Removed bool
+		// source is the name of the source the item was fetched from, set for binding with sources
+		source string
+	}
+
+	// ItemMeta is the common data of items of all the source kinds
+	// built-in filters do not pass items the data they check is missing of
+	ItemMeta struct {
+		// GUID and Link identify the item across the sources of binding with sources, together with the title
+		GUID       string
+		Link       string
+		Title      string
+		Published  *time.Time
+		Categories []string
+		Authors    []string
+	}
+)
+
+var (
+	sourceKindsMux gosync.Mutex
+	sourceKinds    = map[string]SourceFactory{}
+)
+
+// RegisterSource makes the source kind available to the configs under the given name
+// the config of the kind is read from the key with the same name in the source, see DecodeConfig
+func RegisterSource(name string, factory SourceFactory) {
+	sourceKindsMux.Lock()
+	defer sourceKindsMux.Unlock()
+	sourceKinds[name] = factory
+}
+
+// DecodeConfig decodes the config of registered kind into out
+func DecodeConfig(in interface{}, out interface{}) error {
+	b, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, out)
+}
+
+// sourceType returns the kind of the source
+func sourceType(src Source) (string, error) {
+	kinds := []string{}
+	if src.RSS != nil {
+		kinds = append(kinds, sourceKindRSS)
+	}
+	if src.JSON != nil {
+		kinds = append(kinds, sourceKindJSON)
+	}
+	if src.HTML != nil {
+		kinds = append(kinds, sourceKindHTML)
+	}
+	if src.Directory != nil {
+		kinds = append(kinds, sourceKindDirectory)
+	}
+	if src.JIRA != nil {
+		kinds = append(kinds, sourceKindJIRA)
+	}
+	if src.GoogleCalendar != nil {
+		kinds = append(kinds, sourceKindGoogleCalendar)
+	}
+	if src.ICal != nil {
+		kinds = append(kinds, sourceKindICal)
+	}
+	for _, k := range extraKinds(src.Extra) {
+		sourceKindsMux.Lock()
+		_, ok := sourceKinds[k]
+		sourceKindsMux.Unlock()
+		if !ok {
+			return "", fmt.Errorf("Unknown source type \"%s\", supported: %s", k, registeredKinds(sourceKindNames()))
+		}
+		kinds = append(kinds, k)
+	}
+	if len(kinds) != 1 {
+		return "", fmt.Errorf("Source \"%s\" must have exactly one of: %s", src.Name, registeredKinds(sourceKindNames()))
+	}
+	return kinds[0], nil
+}
+
+func buildSourceKind(src Source) (SourceKind, error) {
+	t, err := sourceType(src)
+	if err != nil {
+		return nil, err
+	}
+	sourceKindsMux.Lock()
+	factory, ok := sourceKinds[t]
+	sourceKindsMux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown source type \"%s\", supported: %s", t, registeredKinds(sourceKindNames()))
+	}
+	return factory(src)
+}
+
+func buildSourceKinds(srcs []Source) ([]SourceKind, error) {
+	kinds := []SourceKind{}
+	for _, src := range srcs {
+		kind, err := buildSourceKind(src)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func sourceKindNames() []string {
+	sourceKindsMux.Lock()
+	defer sourceKindsMux.Unlock()
+	names := []string{}
+	for name := range sourceKinds {
+		names = append(names, name)
+	}
+	return names
+}
+
+func extraKinds(extra map[string]interface{}) []string {
+	kinds := []string{}
+	for k := range extra {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func registeredKinds(names []string) string {
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// buildFetchTask returns the task that fetches the sources of the candidates
+// the result is kept by the tracker until the task is finished
+func buildFetchTask(name string, tcs []taskCandidate, kinds []SourceKind, tracker *deliveryTracker) task.Task {
+	// the items of all the sources are needed to find the copies of the same item, sources of binding with sources are not fetched conditionally
+	sourceState := map[string]string{}
+	if len(kinds) == 1 {
+		sourceState = tracker.sourceState(tcs)
+	}
+	return tracker.newTask(name, func(ctx context.Context, opt task.RunOptions) ([]byte, error) {
+		req := FetchRequest{
+			Caller:     tracker.serviceCaller(opt),
+			HTTPClient: tracker.httpClient,
+			FD:         opt.FD.File(),
+			State:      sourceState,
+		}
+		var res FetchResult
+		var err error
+		if len(kinds) == 1 {
+			res, err = kinds[0].Fetch(ctx, req)
+		} else {
+			res, err = fetchSources(ctx, tcs[0].sources, kinds, req)
+		}
+		tracker.fetched(name, res)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Items       int  `json:"items"`
+			Status      int  `json:"status"`
+			NotModified bool `json:"not-modified"`
+		}{len(res.Items), res.Status, res.NotModified})
+	})
+}
+
+// reactToFetchedSource delivers the items of the fetched source to the targets of the binding
+func reactToFetchedSource(cnf Sync, tracker *deliveryTracker) func(ev event.Event, state state.State) []task.Task {
+	return func(ev event.Event, s state.State) []task.Task {
+		name := getBindingNameFromTaskName(ev.Metadata.Task)
+		t := s.Tasks()[ev.Metadata.Task]
+		res := tracker.fetchResult(ev.Metadata.Task)
+		tracker.report.observeFetch(name, t, res.Status)
+		if t.Status != state.TaskStatusSuccess {
+			err := t.Error
+			if err == nil {
+				err = fmt.Errorf("Task %s failed", ev.Metadata.Task)
+			}
+			tracker.report.fail(name, err)
+			return nil
+		}
+		if err := tracker.ctx.Err(); err != nil {
+			tracker.report.fail(name, err)
+			return nil
+		}
+		tracker.report.fetched(name, len(res.Items))
+		return deliverFetchResult(cnf, name, res, tracker)
+	}
+}
+
+// deliverFetchResult returns the tasks that deliver the items of the fetch of the binding to its targets
+// digests of windows that ended are delivered also when the source was not modified
+func deliverFetchResult(cnf Sync, name string, res FetchResult, tracker *deliveryTracker) []task.Task {
+	tcs, err := populateTaskCandidates(name, cnf)
+	if err != nil {
+		tracker.report.fail(name, err)
+		return nil
+	}
+	if res.NotModified {
+		tasks := []task.Task{}
+		for _, tc := range tcs {
+			if tc.binding.Digest != nil {
+				tasks = append(tasks, tracker.deliverDigest(tc, nil)...)
+			}
+		}
+		return tasks
+	}
+	sources := map[string]Source{}
+	filters := map[string][]builtinFilter{}
+	for _, src := range tcs[0].sources {
+		f, err := buildBuiltinFilters(src)
+		if err != nil {
+			tracker.report.fail(name, err)
+			return nil
+		}
+		sources[src.Name] = src
+		filters[src.Name] = f
+	}
+	tracker.keepSourceState(tcs[0], res.State)
+	tasks := []task.Task{}
+	for _, bindingCandidate := range tcs {
+		// items whose key failed are not seen, the fetch is partial for the removal of the items that were not seen
+		partial := res.Partial
+		seen := map[string]bool{}
+		removed := map[string]bool{}
+		entries := []digestEntry{}
+		for i, item := range res.Items {
+			taskCandidate := bindingCandidate
+			if src, ok := sources[item.source]; ok {
+				taskCandidate.src = src
+			}
+			root := buildValues(taskCandidate)
+			for k, v := range item.Data {
+				root.Add(k, v)
+			}
+			key, err := itemKey(taskCandidate.src, root, item.Key)
+			if err != nil {
+				partial = true
+				tracker.report.deliveryFailed(name, fmt.Sprintf("%d %s", i, item.Name), err)
+				continue
+			}
+			if item.Removed {
+				removed[key] = true
+				continue
+			}
+			seen[key] = true
+			if !passBuiltinFilters(filters[taskCandidate.src.Name], item.Meta) || !filterSource(taskCandidate, root, tracker.report) || !filterTarget(taskCandidate, root, tracker.report) {
+				continue
+			}
+			if taskCandidate.binding.Digest != nil {
+				tracker.report.matched(name)
+				if tracker.store.Exists(taskCandidate.bucket(), key) {
+					tracker.report.skipped(name)
+					continue
+				}
+				entries = append(entries, digestEntry{
+					key:  key,
+					data: item.Data,
+				})
+				continue
+			}
+			itemName := item.Name
+			if itemName == "" {
+				itemName = name
+			}
+			// task names must be unique across the bindings, the same item can be fetched by several sources
+			// the log file of the task is named after it, titles like "Episode 1/2" are not valid file names
+			taskName := fmt.Sprintf("%s-%d-created-card-%s", bindingCandidate.bucket(), i, strings.ReplaceAll(itemName, "/", "_"))
+			tasks = append(tasks, tracker.deliver(taskName, taskCandidate, root, key)...)
+		}
+		if bindingCandidate.binding.Digest != nil {
+			tasks = append(tasks, tracker.deliverDigest(bindingCandidate, entries)...)
+		}
+		tasks = append(tasks, tracker.reconcile(bindingCandidate, seen, removed, partial)...)
+	}
+	return tasks
+}
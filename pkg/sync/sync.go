@@ -0,0 +1,267 @@
+package sync
+
+import "errors"
+
+var (
+	errNotFound = errors.New("Not found")
+)
+
+type (
+	Sync struct {
+		// Name identifies the sync in the reports and the metrics, set by Load to the base name of the file
+		Name     string    `json:"-" yaml:"-"`
+		Targets  []Target  `json:"targets" yaml:"targets"`
+		Sources  []Source  `json:"sources" yaml:"sources"`
+		Bindings []Binding `json:"bindings" yaml:"bindings"`
+	}
+
+	Target struct {
+		Name   string `json:"name" yaml:"name"`
+		Trello *struct {
+			Token   string `json:"token" yaml:"token"`
+			Key     string `json:"key" yaml:"key"`
+			BoardID string `json:"board-id" yaml:"board-id"`
+			ListID  string `json:"list-id" yaml:"list-id"`
+			Card    *struct {
+				Title       *string  `json:"title,omitempty" yaml:"title,omitempty"`
+				Description *string  `json:"description,omitempty" yaml:"description,omitempty"`
+				Labels      []string `json:"labels" yaml:"labels"`
+			} `json:"card,omitempty" yaml:"card,omitempty"`
+			// Mode is "add" (default) to add new card for each item
+			// or "upsert" to update the card that was created for the item before
+			Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+		} `json:"trello,omitempty" yaml:"trello,omitempty"`
+		Webhook *struct {
+			URL     string            `json:"url" yaml:"url"`
+			Method  string            `json:"method,omitempty" yaml:"method,omitempty"`
+			Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+			Body    string            `json:"body,omitempty" yaml:"body,omitempty"`
+		} `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+		// Download downloads the enclosure of each item into the directory
+		Download *struct {
+			Directory string `json:"directory" yaml:"directory"`
+			// Filename of the file in the directory, the name of the file in the url with a hash of the url by default
+			// the extension of the url is added when the filename does not end with it
+			Filename string `json:"filename,omitempty" yaml:"filename,omitempty"`
+			// URL of the file, the url of the first enclosure of the item by default
+			URL string `json:"url,omitempty" yaml:"url,omitempty"`
+			// MaxSize skips larger files, e.g. 500MB
+			MaxSize string `json:"max-size,omitempty" yaml:"max-size,omitempty"`
+			// Concurrency is the number of files downloaded at the same time, 1 by default
+			Concurrency int `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+			// Sidecar writes the metadata of the item to <file>.json
+			Sidecar bool `json:"sidecar,omitempty" yaml:"sidecar,omitempty"`
+		} `json:"download,omitempty" yaml:"download,omitempty"`
+		// Feed writes the latest items to RSS 2.0 or Atom file
+		Feed *struct {
+			Path string `json:"path" yaml:"path"`
+			// Format is rss (default) or atom
+			Format      string `json:"format,omitempty" yaml:"format,omitempty"`
+			Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+			Link        string `json:"link,omitempty" yaml:"link,omitempty"`
+			Description string `json:"description,omitempty" yaml:"description,omitempty"`
+			// MaxItems is the number of latest items the feed keeps, 50 by default
+			MaxItems int `json:"max-items,omitempty" yaml:"max-items,omitempty"`
+			// State is the file the items are kept in, <path>.state.json by default
+			State string `json:"state,omitempty" yaml:"state,omitempty"`
+			// Item templates, the title, link, description and publishedParsed of rss items by default
+			Item struct {
+				Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+				Link        string `json:"link,omitempty" yaml:"link,omitempty"`
+				Description string `json:"description,omitempty" yaml:"description,omitempty"`
+				// Published renders RFC3339 time, the time the item was added by default
+				Published string `json:"published,omitempty" yaml:"published,omitempty"`
+			} `json:"item,omitempty" yaml:"item,omitempty"`
+		} `json:"feed,omitempty" yaml:"feed,omitempty"`
+		// Extra holds the config of target kinds added with RegisterTarget, keyed by the kind name
+		Extra map[string]interface{} `json:"-" yaml:",inline"`
+	}
+
+	Source struct {
+		Name string `json:"name" yaml:"name"`
+		RSS  *struct {
+			URL  string `json:"url" yaml:"url"`
+			Auth *struct {
+				Username string `json:"username" yaml:"username"`
+				Password string `json:"password" yaml:"password"`
+			} `json:"auth" yaml:"auth"`
+		} `json:"rss,omitempty" yaml:"rss,omitempty"`
+		JSON *struct {
+			URL  string `json:"url" yaml:"url"`
+			Type string `json:"type" yaml:"type"`
+			// ItemsPath is jq expression that selects the items in the response, e.g. .data.items, used instead of type
+			ItemsPath string `json:"items-path,omitempty" yaml:"items-path,omitempty"`
+			// KeyPath is jq expression that selects the key of the item, e.g. .id
+			KeyPath string `json:"key-path,omitempty" yaml:"key-path,omitempty"`
+		} `json:"json,omitempty" yaml:"json,omitempty"`
+		// HTML scrapes the items of the page using css selectors
+		HTML *struct {
+			URL string `json:"url" yaml:"url"`
+			// Item selects the element of each item, the other selectors are relative to it
+			Item  string `json:"item" yaml:"item"`
+			Title string `json:"title,omitempty" yaml:"title,omitempty"`
+			// Link selects the element with the href of the item, the first link in the item by default
+			Link        string `json:"link,omitempty" yaml:"link,omitempty"`
+			Date        string `json:"date,omitempty" yaml:"date,omitempty"`
+			Description string `json:"description,omitempty" yaml:"description,omitempty"`
+			// DateFormat is the go layout of the date, e.g. "January 2, 2006", common formats are tried by default
+			DateFormat string `json:"date-format,omitempty" yaml:"date-format,omitempty"`
+		} `json:"html,omitempty" yaml:"html,omitempty"`
+		// Directory reads the files in the directory, non recursive
+		Directory *struct {
+			Path string `json:"path" yaml:"path"`
+			// Pattern the names of the files must match, e.g. *.json, all the files by default
+			Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+			// Format of the files: json (default) or rss
+			Format string `json:"format,omitempty" yaml:"format,omitempty"`
+			// Type of json files: object (default) or array
+			Type string `json:"type,omitempty" yaml:"type,omitempty"`
+		} `json:"directory,omitempty" yaml:"directory,omitempty"`
+		JIRA *struct {
+			User     string `json:"user" yaml:"user"`
+			Token    string `json:"token" yaml:"token"`
+			Endpoint string `json:"endpoint" yaml:"endpoint"`
+			JQL      string `json:"jql" yaml:"jql"`
+		} `json:"jira,omitempty" yaml:"jira,omitempty"`
+		GoogleCalendar *struct {
+			ServiceAccount string `json:"service-account" yaml:"service-account"`
+			CalendarID     string `json:"calendar-id" yaml:"calendar-id"`
+			TimeMin        string `json:"time-min" yaml:"time-min"`
+			TimeMax        string `json:"time-max" yaml:"time-max"`
+		} `json:"google-calendar" yaml:"google-calendar"`
+		// ICal reads the events of iCalendar (.ics) file, recurring events are expanded between time-min and time-max
+		ICal *struct {
+			URL string `json:"url" yaml:"url"`
+			// TimeMin and TimeMax are RFC3339 times, now and a year after time-min by default
+			TimeMin string `json:"time-min,omitempty" yaml:"time-min,omitempty"`
+			TimeMax string `json:"time-max,omitempty" yaml:"time-max,omitempty"`
+		} `json:"ical,omitempty" yaml:"ical,omitempty"`
+		Filter map[string]string `json:"filter" yaml:"filter"`
+		// MaxAge passes items published in the given duration, e.g. 24h
+		MaxAge string `json:"max-age,omitempty" yaml:"max-age,omitempty"`
+		// TitleMatches passes items with title that matches any of the regular expressions
+		TitleMatches []string `json:"title-matches,omitempty" yaml:"title-matches,omitempty"`
+		// TitleExcludes passes items with title that matches none of the regular expressions
+		TitleExcludes []string `json:"title-excludes,omitempty" yaml:"title-excludes,omitempty"`
+		// CategoriesAny passes items with any of the categories, case insensitive
+		CategoriesAny []string `json:"categories-any,omitempty" yaml:"categories-any,omitempty"`
+		// AuthorIn passes items with author name or email that is one of the list, case insensitive
+		AuthorIn []string `json:"author-in,omitempty" yaml:"author-in,omitempty"`
+		// Key is a template that renders the key the item is identified by in the state store
+		Key string `json:"key,omitempty" yaml:"key,omitempty"`
+		// Interval between two fetches of the source when running in serve mode, e.g. 30m
+		Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+		// Cron expression that schedules the fetches of the source when running in serve mode
+		Cron string `json:"cron,omitempty" yaml:"cron,omitempty"`
+		// ConditionalGet sends the ETag and Last-Modified of the previous response of rss and json sources
+		// an unchanged source returns no items, enabled by default, used by the native engine only
+		ConditionalGet *bool `json:"conditional-get,omitempty" yaml:"conditional-get,omitempty"`
+		// Extra holds the config of source kinds added with RegisterSource, keyed by the kind name
+		Extra map[string]interface{} `json:"-" yaml:",inline"`
+	}
+
+	Binding struct {
+		Name   string `json:"name" yaml:"name"`
+		Source string `json:"source" yaml:"source"`
+		// Sources merges the items of several sources, used instead of source
+		Sources []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+		Target  string   `json:"target" yaml:"target"`
+		// Targets delivers the items of one fetch of the source to several targets, used instead of target
+		Targets []BindingTarget `json:"targets,omitempty" yaml:"targets,omitempty"`
+		// OnRemoved is the action to take on cards of items that are no longer returned by the source or are cancelled
+		// sources that return only some of their items, e.g. the events of the time window, report only the cancelled ones
+		// it requires trello target in upsert mode, only upsert mode stores the id of the card of the item
+		OnRemoved *OnRemoved `json:"on-removed,omitempty" yaml:"on-removed,omitempty"`
+		// Digest delivers all the items that passed the filters as one item
+		Digest *Digest `json:"digest,omitempty" yaml:"digest,omitempty"`
+	}
+
+	// BindingTarget is one of the targets of a binding, set as the name or as name and filter
+	BindingTarget struct {
+		Name string `json:"name" yaml:"name"`
+		// Filter must pass, in addition to the filters of the source, for the item to be delivered to the target
+		Filter map[string]string `json:"filter,omitempty" yaml:"filter,omitempty"`
+	}
+
+	Digest struct {
+		// Window the items are grouped by: run (default), daily or weekly
+		Window string `json:"window,omitempty" yaml:"window,omitempty"`
+	}
+
+	OnRemoved struct {
+		Archive    bool   `json:"archive,omitempty" yaml:"archive,omitempty"`
+		MoveToList string `json:"move-to-list,omitempty" yaml:"move-to-list,omitempty"`
+		Comment    string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	}
+)
+
+func getBinding(name string, list []Binding) (Binding, error) {
+	for _, b := range list {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+
+	return Binding{}, errNotFound
+}
+
+func getSource(name string, list []Source) (Source, error) {
+	for _, b := range list {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+
+	return Source{}, errNotFound
+}
+
+func getTarget(name string, list []Target) (Target, error) {
+	for _, b := range list {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Target{}, errNotFound
+}
+
+// UnmarshalYAML reads the target from its name or from name and filter
+func (t *BindingTarget) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	name := ""
+	if err := unmarshal(&name); err == nil {
+		t.Name = name
+		return nil
+	}
+	type bindingTarget BindingTarget
+	return unmarshal((*bindingTarget)(t))
+}
+
+// SourceNames returns the names of the sources of the binding
+func (b Binding) SourceNames() []string {
+	if len(b.Sources) == 0 {
+		return []string{b.Source}
+	}
+	return b.Sources
+}
+
+// targetsOf returns the targets of the binding, binding with target has the one target without filter
+func targetsOf(binding Binding) []BindingTarget {
+	if len(binding.Targets) == 0 {
+		return []BindingTarget{{Name: binding.Target}}
+	}
+	return binding.Targets
+}
+
+// FindSource returns the source with the given name
+func (s Sync) FindSource(name string) (Source, error) {
+	return getSource(name, s.Sources)
+}
+
+// ForBinding returns copy of the sync that runs only the given binding
+func ForBinding(cnf Sync, binding Binding) Sync {
+	return Sync{
+		Name:     cnf.Name,
+		Targets:  cnf.Targets,
+		Sources:  cnf.Sources,
+		Bindings: []Binding{binding},
+	}
+}
@@ -0,0 +1,204 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	gosync "sync"
+
+	"github.com/olegsu/rss-sync/pkg/store"
+	"github.com/open-integration/core/pkg/task"
+	"github.com/open-integration/service-catalog/http/pkg/endpoints/call"
+)
+
+const (
+	targetKindTrello  = "trello"
+	targetKindWebhook = "webhook"
+)
+
+type (
+	// TargetKind delivers items to one configured target
+	TargetKind interface {
+		// Plan returns what delivering the item would do without delivering it
+		// the action is empty when the item should be skipped
+		Plan(req DeliveryRequest) (Planned, error)
+		// Deliver delivers the item and returns the data that is stored with the item
+		Deliver(ctx context.Context, req DeliveryRequest) (map[string]string, error)
+	}
+
+	// Remover is implemented by target kinds that can act on items that were removed from the source
+	Remover interface {
+		PlanRemove(req DeliveryRequest, onRemoved OnRemoved) (Planned, error)
+		Remove(ctx context.Context, req DeliveryRequest, onRemoved OnRemoved) error
+	}
+
+	// TargetFactory builds the target kind from the config of the target
+	TargetFactory func(target Target) (TargetKind, error)
+
+	DeliveryRequest struct {
+		Caller ServiceCaller
+		// HTTPClient is used for requests the target sends from the process
+		HTTPClient *http.Client
+		// FD is the log file of the task that runs the delivery
+		FD  string
+		Key string
+		// Data is the template data of the item
+		Data interface{}
+		// Previous is the record stored when the item was delivered before, nil otherwise
+		Previous *store.Record
+	}
+
+	// Planned is the result of planning the delivery of one item
+	Planned struct {
+		Action string
+		// Title, Description and Labels summarize the item in the plan table
+		Title       string
+		Description string
+		Labels      []string
+		// Output is the rendered item as it would be delivered
+		Output interface{}
+	}
+
+	httpCall struct {
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    string            `json:"body,omitempty"`
+	}
+)
+
+var (
+	targetKindsMux gosync.Mutex
+	targetKinds    = map[string]TargetFactory{}
+)
+
+// RegisterTarget makes the target kind available to the configs under the given name
+// the config of the kind is read from the key with the same name in the target, see DecodeConfig
+func RegisterTarget(name string, factory TargetFactory) {
+	targetKindsMux.Lock()
+	defer targetKindsMux.Unlock()
+	targetKinds[name] = factory
+}
+
+// targetType returns the kind of the target
+func targetType(target Target) (string, error) {
+	kinds := []string{}
+	if target.Trello != nil {
+		kinds = append(kinds, targetKindTrello)
+	}
+	if target.Webhook != nil {
+		kinds = append(kinds, targetKindWebhook)
+	}
+	if target.Download != nil {
+		kinds = append(kinds, targetKindDownload)
+	}
+	if target.Feed != nil {
+		kinds = append(kinds, targetKindFeed)
+	}
+	for _, k := range extraKinds(target.Extra) {
+		targetKindsMux.Lock()
+		_, ok := targetKinds[k]
+		targetKindsMux.Unlock()
+		if !ok {
+			return "", fmt.Errorf("Unknown target type \"%s\", supported: %s", k, registeredKinds(targetKindNames()))
+		}
+		kinds = append(kinds, k)
+	}
+	if len(kinds) != 1 {
+		return "", fmt.Errorf("Target \"%s\" must have exactly one of: %s", target.Name, registeredKinds(targetKindNames()))
+	}
+	return kinds[0], nil
+}
+
+func buildTargetKind(target Target) (TargetKind, error) {
+	t, err := targetType(target)
+	if err != nil {
+		return nil, err
+	}
+	targetKindsMux.Lock()
+	factory, ok := targetKinds[t]
+	targetKindsMux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown target type \"%s\", supported: %s", t, registeredKinds(targetKindNames()))
+	}
+	return factory(target)
+}
+
+func targetKindNames() []string {
+	targetKindsMux.Lock()
+	defer targetKindsMux.Unlock()
+	names := []string{}
+	for name := range targetKinds {
+		names = append(names, name)
+	}
+	return names
+}
+
+// callService calls the endpoint of the service with the arguments and unmarshals the response into out
+func callService(ctx context.Context, caller ServiceCaller, fd string, service string, endpoint string, arguments []task.Argument, out interface{}) error {
+	args := map[string]interface{}{}
+	for _, arg := range arguments {
+		args[arg.Key] = arg.Value
+	}
+	res, err := caller.Call(ctx, service, endpoint, args, fd)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(res, out)
+}
+
+// callHTTP sends the request using the http service
+// unsuccessful response status is returned as error
+func callHTTP(ctx context.Context, caller ServiceCaller, fd string, c httpCall) (call.CallReturns, error) {
+	res := call.CallReturns{}
+	if err := callService(ctx, caller, fd, "http", "call", httpCallArguments(c), &res); err != nil {
+		return res, err
+	}
+	if res.Status >= 300 {
+		return res, fmt.Errorf("Request returned status %d: %s", res.Status, res.Body)
+	}
+	return res, nil
+}
+
+func httpCallArguments(c httpCall) []task.Argument {
+	names := []string{}
+	for k := range c.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	headers := []call.Header{}
+	for _, n := range names {
+		n := n
+		v := c.Headers[n]
+		headers = append(headers, call.Header{
+			Name:  &n,
+			Value: &v,
+		})
+	}
+	arguments := []task.Argument{
+		{
+			Key:   "URL",
+			Value: c.URL,
+		},
+		{
+			Key:   "Verb",
+			Value: c.Method,
+		},
+		{
+			Key:   "Headers",
+			Value: headers,
+		},
+	}
+	if c.Body != "" {
+		arguments = append(arguments, task.Argument{
+			Key:   "Content",
+			Value: c.Body,
+		})
+	}
+	return arguments
+}
@@ -0,0 +1,283 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/open-integration/core/pkg/task"
+	"github.com/open-integration/service-catalog/http/pkg/endpoints/call"
+)
+
+const (
+	trelloAPI = "https://api.trello.com/1"
+
+	trelloModeAdd    = "add"
+	trelloModeUpsert = "upsert"
+
+	// keys of the data stored for each delivered item
+	storeKeyCardID = "card-id"
+	storeKeyHash   = "hash"
+)
+
+type (
+	trelloCard struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+	}
+
+	trelloTarget struct {
+		target Target
+	}
+)
+
+func init() {
+	RegisterTarget(targetKindTrello, func(target Target) (TargetKind, error) {
+		if target.Trello == nil {
+			return nil, fmt.Errorf("Target \"%s\" has no trello config", target.Name)
+		}
+		if target.Trello.Card == nil {
+			return nil, fmt.Errorf("Trello target \"%s\" must have card", target.Name)
+		}
+		return &trelloTarget{target: target}, nil
+	})
+}
+
+func isTrelloUpsert(target Target) bool {
+	return target.Trello != nil && target.Trello.Mode == trelloModeUpsert
+}
+
+// Plan skips items that were already delivered
+// in upsert mode the card is updated when it was changed since the last update
+func (t *trelloTarget) Plan(req DeliveryRequest) (Planned, error) {
+	card, err := renderTrelloCard(t.target, req.Data)
+	if err != nil {
+		return Planned{}, err
+	}
+	planned := Planned{
+		Action:      actionCreate,
+		Title:       card.Title,
+		Description: card.Description,
+		Labels:      card.Labels,
+		Output:      card,
+	}
+	if req.Previous == nil {
+		return planned, nil
+	}
+	cardID := req.Previous.Data[storeKeyCardID]
+	if !isTrelloUpsert(t.target) || req.Key == "" || cardID == "" || req.Previous.Data[storeKeyHash] == card.hash() {
+		// delivered without upsert mode or not changed since the last update
+		return Planned{}, nil
+	}
+	planned.Action = actionUpdate
+	return planned, nil
+}
+
+// Deliver adds the card using the trello service
+// in upsert mode the card is created or updated using the Trello REST API
+// and the id of the card is returned to be stored with the item
+func (t *trelloTarget) Deliver(ctx context.Context, req DeliveryRequest) (map[string]string, error) {
+	card, err := renderTrelloCard(t.target, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	if !isTrelloUpsert(t.target) || req.Key == "" {
+		return nil, callService(ctx, req.Caller, req.FD, "trello", "addcard", trelloAddCardArguments(t.target, card), nil)
+	}
+	cardID := ""
+	if req.Previous != nil {
+		cardID = req.Previous.Data[storeKeyCardID]
+	}
+	res, err := callHTTP(ctx, req.Caller, req.FD, trelloCardCall(t.target, cardID, card))
+	if err != nil {
+		return nil, err
+	}
+	if cardID == "" {
+		cardID, err = trelloCardID(res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return map[string]string{
+		storeKeyCardID: cardID,
+		storeKeyHash:   card.hash(),
+	}, nil
+}
+
+// PlanRemove skips items whose card id is not known, only cards created in upsert mode are known
+func (t *trelloTarget) PlanRemove(req DeliveryRequest, onRemoved OnRemoved) (Planned, error) {
+	if req.Previous == nil || req.Previous.Data[storeKeyCardID] == "" {
+		return Planned{}, nil
+	}
+	action, _, err := trelloRemovedCardCall(t.target, req.Previous.Data[storeKeyCardID], onRemoved, req.Data)
+	if err != nil {
+		return Planned{}, err
+	}
+	return Planned{
+		Action: action,
+	}, nil
+}
+
+func (t *trelloTarget) Remove(ctx context.Context, req DeliveryRequest, onRemoved OnRemoved) error {
+	_, c, err := trelloRemovedCardCall(t.target, req.Previous.Data[storeKeyCardID], onRemoved, req.Data)
+	if err != nil {
+		return err
+	}
+	_, err = callHTTP(ctx, req.Caller, req.FD, c)
+	return err
+}
+
+// renderTrelloCard returns the card of the item, the item is not delivered when one of the templates fails
+// labels are rendered without the data of the item
+func renderTrelloCard(target Target, data interface{}) (trelloCard, error) {
+	card := trelloCard{
+		Labels: []string{},
+	}
+	var err error
+	if target.Trello.Card.Title != nil {
+		if card.Title, err = renderField("card.title", *target.Trello.Card.Title, data); err != nil {
+			return card, err
+		}
+	}
+	if target.Trello.Card.Description != nil {
+		if card.Description, err = renderField("card.description", *target.Trello.Card.Description, data); err != nil {
+			return card, err
+		}
+	}
+	for _, l := range target.Trello.Card.Labels {
+		label, err := renderField("card.labels", l, nil)
+		if err != nil {
+			return card, err
+		}
+		card.Labels = append(card.Labels, label)
+	}
+	return card, nil
+}
+
+// hash returns hash of the card content, used to detect changes since the last update
+func (c trelloCard) hash() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	h := sha1.Sum(b)
+	return hex.EncodeToString(h[:])
+}
+
+func trelloAddCardArguments(target Target, card trelloCard) []task.Argument {
+	return []task.Argument{
+		{
+			Key:   "App",
+			Value: template.String(&target.Trello.Key, nil),
+		},
+		{
+			Key:   "Token",
+			Value: template.String(&target.Trello.Token, nil),
+		},
+		{
+			Key:   "Board",
+			Value: template.String(&target.Trello.BoardID, nil),
+		},
+		{
+			Key:   "List",
+			Value: template.String(&target.Trello.ListID, nil),
+		},
+		{
+			Key:   "Name",
+			Value: card.Title,
+		},
+		{
+			Key:   "Description",
+			Value: card.Description,
+		},
+		{
+			Key:   "Labels",
+			Value: card.Labels,
+		},
+	}
+}
+
+// trelloCardCall returns the call to the Trello REST API that creates the card
+// or updates the card with the given id
+func trelloCardCall(target Target, cardID string, card trelloCard) httpCall {
+	body := map[string]interface{}{
+		"name":     card.Title,
+		"desc":     card.Description,
+		"idLabels": strings.Join(card.Labels, ","),
+	}
+	method := "PUT"
+	u := fmt.Sprintf("%s/cards/%s", trelloAPI, url.PathEscape(cardID))
+	if cardID == "" {
+		method = "POST"
+		u = fmt.Sprintf("%s/cards", trelloAPI)
+		body["idList"] = template.String(&target.Trello.ListID, nil)
+		body["pos"] = "bottom"
+	}
+	return trelloAPICall(target, method, u, body)
+}
+
+// trelloAPICall returns call to the Trello REST API authenticated with the key and the token of the target
+func trelloAPICall(target Target, method string, u string, body map[string]interface{}) httpCall {
+	q := url.Values{}
+	q.Set("key", template.String(&target.Trello.Key, nil))
+	q.Set("token", template.String(&target.Trello.Token, nil))
+	c := httpCall{
+		URL:    fmt.Sprintf("%s?%s", u, q.Encode()),
+		Method: method,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"Accept":       "application/json",
+		},
+	}
+	if body != nil {
+		b, _ := json.Marshal(body)
+		c.Body = string(b)
+	}
+	return c
+}
+
+// trelloRemovedCardCall returns the call to the Trello REST API that applies the on-removed action on the card
+func trelloRemovedCardCall(target Target, cardID string, onRemoved OnRemoved, data interface{}) (string, httpCall, error) {
+	u := fmt.Sprintf("%s/cards/%s", trelloAPI, url.PathEscape(cardID))
+	if onRemoved.MoveToList != "" {
+		list, err := renderField("on-removed.move-to-list", onRemoved.MoveToList, data)
+		if err != nil {
+			return "", httpCall{}, err
+		}
+		return actionMove, trelloAPICall(target, "PUT", u, map[string]interface{}{
+			"idList": list,
+		}), nil
+	}
+	if onRemoved.Comment != "" {
+		text, err := renderField("on-removed.comment", onRemoved.Comment, data)
+		if err != nil {
+			return "", httpCall{}, err
+		}
+		return actionComment, trelloAPICall(target, "POST", u+"/actions/comments", map[string]interface{}{
+			"text": text,
+		}), nil
+	}
+	return actionArchive, trelloAPICall(target, "PUT", u, map[string]interface{}{
+		"closed": true,
+	}), nil
+}
+
+// trelloCardID reads the id of the card from the response
+func trelloCardID(res call.CallReturns) (string, error) {
+	card := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal([]byte(res.Body), &card); err != nil {
+		return "", fmt.Errorf("Failed to read card id from response: %w", err)
+	}
+	if card.ID == "" {
+		return "", fmt.Errorf("Response has no card id")
+	}
+	return card.ID, nil
+}
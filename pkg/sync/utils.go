@@ -0,0 +1,250 @@
+package sync
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/olegsu/rss-sync/pkg/expression"
+	"github.com/olegsu/rss-sync/pkg/template"
+	"github.com/olegsu/rss-sync/pkg/values"
+	"github.com/open-integration/service-catalog/google-calendar/pkg/endpoints/getEvents"
+	"github.com/open-integration/service-catalog/jira/pkg/endpoints/list"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	seperator = ":::"
+)
+
+var (
+	// filterVariables are the values the filters can use, the data of the sources and the config of the binding
+	filterVariables = []string{"item", "feed", "issue", "event", "content", "file", "source", "binding", "target"}
+)
+
+type (
+	// targetTemplateError is returned when the template of a field of a target failed on the item
+	targetTemplateError struct {
+		field string
+		err   error
+	}
+)
+
+func (e *targetTemplateError) Error() string {
+	return fmt.Sprintf("Template of %s failed: %v", e.field, e.err)
+}
+
+func (e *targetTemplateError) Unwrap() error {
+	return e.err
+}
+
+func readFile(location string) (Sync, error) {
+	bytes, err := ioutil.ReadFile(location)
+	if err != nil {
+		return Sync{}, err
+	}
+	cnf := Sync{}
+	if err := yaml.Unmarshal(bytes, &cnf); err != nil {
+		return cnf, err
+	}
+	return cnf, nil
+}
+
+func buildURL(URL string, username string, password string) (string, error) {
+	u, err := url.Parse(template.String(&URL, nil))
+	if err != nil {
+		return "", err
+	}
+	if username != "" && password != "" {
+		u.User = url.UserPassword(template.String(&username, nil), template.String(&password, nil))
+	}
+	return u.String(), nil
+}
+
+// filter returns the result of the filter on the data
+// filters with "{{" are go templates that must render "true" or "false", others are expressions
+func filter(data *values.Values, filter string) (bool, error) {
+	if !expression.IsTemplate(filter) {
+		return expression.Bool(filter, filterVariables, *data)
+	}
+	out, err := template.Render(filter, data)
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(out) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("Template rendered \"%s\", expected true or false", out)
+}
+
+// renderField executes the template of the field of the target on the data
+// the error names the field, the same template is rendered for each item
+func renderField(field string, tmpl string, data interface{}) (string, error) {
+	out, err := template.Render(tmpl, data)
+	if err != nil {
+		return "", &targetTemplateError{
+			field: field,
+			err:   err,
+		}
+	}
+	return out, nil
+}
+
+func buildTaskName(binding Binding) string {
+	return fmt.Sprintf("%s%s%s", binding.Name, seperator, strings.Join(binding.SourceNames(), ","))
+}
+
+func getBindingNameFromTaskName(name string) string {
+	return strings.Split(name, seperator)[0]
+}
+
+// itemKey returns the key that identifies the item in the state store
+// the source key template is used when set, otherwise the given default
+// items without key cannot be deduplicated, an empty key is an error
+func itemKey(src Source, data interface{}, def string) (string, error) {
+	key := def
+	if src.Key != "" {
+		k, err := renderField("key", src.Key, data)
+		if err != nil {
+			return "", err
+		}
+		key = k
+		// a missing field of the item renders as "<no value>", the same key for all the items
+		if key == "<no value>" {
+			key = ""
+		}
+	}
+	if key == "" {
+		return "", fmt.Errorf("Key of the item of source %s is empty", src.Name)
+	}
+	return key, nil
+}
+
+func gofeedItemKey(item gofeed.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return item.Title
+}
+
+func jiraIssueKey(issue list.Issue) string {
+	if issue.Key == nil {
+		return ""
+	}
+	return *issue.Key
+}
+
+func googleCalendarEventKey(ev getEvents.Event) string {
+	if ev.ID == nil {
+		return ""
+	}
+	return *ev.ID
+}
+
+// jsonContentKey returns the "id" of the content when exists
+// or hash of the content otherwise
+func jsonContentKey(content map[string]interface{}) string {
+	if id, ok := content["id"]; ok && id != nil {
+		return fmt.Sprintf("%v", id)
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	h := sha1.Sum(b)
+	return hex.EncodeToString(h[:])
+}
+
+func gofeedItemToJSON(item gofeed.Item) map[string]interface{} {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func jiraIssueToJSON(issue list.Issue) map[string]interface{} {
+	b, err := json.Marshal(issue)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func googleCalendarEventToJSON(ev getEvents.Event) map[string]interface{} {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func srcToJSON(src Source) map[string]interface{} {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func bindingToJSON(binding Binding) map[string]interface{} {
+	b, err := json.Marshal(binding)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func targetToJSON(target Target) map[string]interface{} {
+	b, err := json.Marshal(target)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func feedToJSON(feed gofeed.Feed) map[string]interface{} {
+	feed.Items = []*gofeed.Item{}
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return nil
+	}
+	return toJSON(b)
+}
+
+func toJSON(input []byte) map[string]interface{} {
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(input, &data); err != nil {
+		return data
+	}
+	return data
+}
+func toArrayJSON(input []byte) []map[string]interface{} {
+	data := []map[string]interface{}{}
+	if err := json.Unmarshal(input, &data); err != nil {
+		return data
+	}
+	return data
+}
+
+// itemData returns the "item" and "feed" of the template data
+func itemData(data interface{}) (map[string]interface{}, map[string]interface{}) {
+	v, ok := data.(*values.Values)
+	if !ok || v == nil {
+		return nil, nil
+	}
+	item, _ := (*v)["item"].(map[string]interface{})
+	feed, _ := (*v)["feed"].(map[string]interface{})
+	return item, feed
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/olegsu/rss-sync/pkg/values"
+)
+
+func TestItemKey(t *testing.T) {
+	data := &values.Values{
+		"item": map[string]interface{}{
+			"link": "https://example.com/1",
+		},
+	}
+	tests := []struct {
+		name    string
+		key     string
+		def     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			def:  "guid",
+			want: "guid",
+		},
+		{
+			name: "template",
+			key:  "{{ .item.link }}",
+			def:  "guid",
+			want: "https://example.com/1",
+		},
+		{
+			name:    "malformed template",
+			key:     "{{ .item.link ",
+			def:     "guid",
+			wantErr: true,
+		},
+		{
+			name:    "template that fails",
+			key:     "{{ .item.link.host }}",
+			def:     "guid",
+			wantErr: true,
+		},
+		{
+			name:    "template that renders empty",
+			key:     "{{ .item.title }}",
+			def:     "guid",
+			wantErr: true,
+		},
+		{
+			name:    "empty default",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := itemKey(Source{Name: "src", Key: tt.key}, data, tt.def)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("itemKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("itemKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
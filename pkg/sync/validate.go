@@ -0,0 +1,451 @@
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/olegsu/rss-sync/pkg/expression"
+	"github.com/olegsu/rss-sync/pkg/template"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+type (
+	// configError is an error found in the config at the given path, e.g. sources[0].rss.url
+	configError struct {
+		path string
+		msg  string
+	}
+
+	// validationError is a configError with its position in the file
+	validationError struct {
+		file   string
+		line   int
+		column int
+		msg    string
+	}
+)
+
+func (e validationError) Error() string {
+	if e.line == 0 {
+		return fmt.Sprintf("%s: %s", e.file, e.msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.file, e.line, e.column, e.msg)
+}
+
+// ValidateFile validates the config file and returns all the errors found
+func ValidateFile(location string) []error {
+	b, err := ioutil.ReadFile(location)
+	if err != nil {
+		return []error{err}
+	}
+	root := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(b, &root); err != nil {
+		return []error{validationError{
+			file: location,
+			msg:  err.Error(),
+		}}
+	}
+	cnf, err := readFile(location)
+	if err != nil {
+		return []error{validationError{
+			file: location,
+			msg:  err.Error(),
+		}}
+	}
+	index := map[string]*yamlv3.Node{}
+	indexNodes(&root, "", index)
+	errs := []error{}
+	for _, e := range validateSync(cnf) {
+		ve := validationError{
+			file: location,
+			msg:  e.msg,
+		}
+		if n := lookupNode(index, e.path); n != nil {
+			ve.line = n.Line
+			ve.column = n.Column
+		}
+		errs = append(errs, ve)
+	}
+	return errs
+}
+
+// validateSync checks the references between the bindings, sources and targets
+// and that all the templates can be parsed
+func validateSync(cnf Sync) []configError {
+	errs := []configError{}
+	add := func(path string, format string, a ...interface{}) {
+		errs = append(errs, configError{
+			path: path,
+			msg:  fmt.Sprintf(format, a...),
+		})
+	}
+	tmpl := func(path string, t string) {
+		if err := template.Validate(t); err != nil {
+			add(path, "Failed to parse template: %v", err)
+		}
+	}
+	validateFilter := func(path string, filters map[string]string) {
+		for _, name := range sortedKeys(filters) {
+			f := filters[name]
+			if expression.IsTemplate(f) {
+				tmpl(fmt.Sprintf("%s.filter.%s", path, name), f)
+			} else if err := expression.Validate(f, filterVariables); err != nil {
+				add(fmt.Sprintf("%s.filter.%s", path, name), "Failed to compile expression: %v", err)
+			}
+		}
+	}
+
+	sources := map[string]bool{}
+	for i, src := range cnf.Sources {
+		p := fmt.Sprintf("sources[%d]", i)
+		if src.Name == "" {
+			add(p, "Source name is required")
+		} else if sources[src.Name] {
+			add(p+".name", "Duplicate source name \"%s\"", src.Name)
+		}
+		sources[src.Name] = true
+
+		if src.RSS != nil {
+			tmpl(p+".rss.url", src.RSS.URL)
+			if src.RSS.Auth != nil {
+				tmpl(p+".rss.auth.username", src.RSS.Auth.Username)
+				tmpl(p+".rss.auth.password", src.RSS.Auth.Password)
+			}
+		}
+		if src.JSON != nil {
+			tmpl(p+".json.url", src.JSON.URL)
+			if src.JSON.Type != "" && src.JSON.Type != "object" && src.JSON.Type != "array" {
+				add(p+".json.type", "Unknown json type \"%s\", supported: object, array", src.JSON.Type)
+			}
+			if src.JSON.ItemsPath != "" {
+				if src.JSON.Type != "" {
+					add(p+".json.items-path", "items-path cannot be used together with type")
+				}
+				if _, err := compileJQ(src.JSON.ItemsPath); err != nil {
+					add(p+".json.items-path", "Failed to parse jq expression: %v", err)
+				}
+			}
+			if src.JSON.KeyPath != "" {
+				if _, err := compileJQ(src.JSON.KeyPath); err != nil {
+					add(p+".json.key-path", "Failed to parse jq expression: %v", err)
+				}
+			}
+		}
+		if src.HTML != nil {
+			tmpl(p+".html.url", src.HTML.URL)
+			if src.HTML.Item == "" {
+				add(p+".html.item", "Item selector is required")
+			}
+			selectors := map[string]string{
+				"item":        src.HTML.Item,
+				"title":       src.HTML.Title,
+				"link":        src.HTML.Link,
+				"date":        src.HTML.Date,
+				"description": src.HTML.Description,
+			}
+			for _, name := range sortedKeys(selectors) {
+				if selectors[name] == "" {
+					continue
+				}
+				if _, err := cascadia.Compile(selectors[name]); err != nil {
+					add(fmt.Sprintf("%s.html.%s", p, name), "Invalid selector: %v", err)
+				}
+			}
+		}
+		if src.Directory != nil {
+			tmpl(p+".directory.path", src.Directory.Path)
+			if _, err := filepath.Match(src.Directory.Pattern, ""); err != nil {
+				add(p+".directory.pattern", "Invalid pattern: %v", err)
+			}
+			if src.Directory.Format != "" && src.Directory.Format != directoryFormatJSON && src.Directory.Format != directoryFormatRSS {
+				add(p+".directory.format", "Unknown format \"%s\", supported: json, rss", src.Directory.Format)
+			}
+			if src.Directory.Type != "" && src.Directory.Type != "object" && src.Directory.Type != "array" {
+				add(p+".directory.type", "Unknown json type \"%s\", supported: object, array", src.Directory.Type)
+			}
+		}
+		if src.JIRA != nil {
+			tmpl(p+".jira.user", src.JIRA.User)
+			tmpl(p+".jira.token", src.JIRA.Token)
+			tmpl(p+".jira.endpoint", src.JIRA.Endpoint)
+			tmpl(p+".jira.jql", src.JIRA.JQL)
+		}
+		if src.GoogleCalendar != nil {
+			tmpl(p+".google-calendar.service-account", src.GoogleCalendar.ServiceAccount)
+			tmpl(p+".google-calendar.calendar-id", src.GoogleCalendar.CalendarID)
+			tmpl(p+".google-calendar.time-min", src.GoogleCalendar.TimeMin)
+			tmpl(p+".google-calendar.time-max", src.GoogleCalendar.TimeMax)
+		}
+		if src.ICal != nil {
+			tmpl(p+".ical.url", src.ICal.URL)
+			tmpl(p+".ical.time-min", src.ICal.TimeMin)
+			tmpl(p+".ical.time-max", src.ICal.TimeMax)
+		}
+		if _, err := buildSourceKind(src); err != nil {
+			add(p, "%v", err)
+		}
+		validateFilter(p, src.Filter)
+		if src.Key != "" {
+			tmpl(p+".key", src.Key)
+		}
+		if src.MaxAge != "" {
+			if _, err := time.ParseDuration(src.MaxAge); err != nil {
+				add(p+".max-age", "Invalid max-age: %v", err)
+			}
+		}
+		for j, e := range src.TitleMatches {
+			if _, err := regexp.Compile(e); err != nil {
+				add(fmt.Sprintf("%s.title-matches[%d]", p, j), "Invalid regular expression: %v", err)
+			}
+		}
+		for j, e := range src.TitleExcludes {
+			if _, err := regexp.Compile(e); err != nil {
+				add(fmt.Sprintf("%s.title-excludes[%d]", p, j), "Invalid regular expression: %v", err)
+			}
+		}
+		if _, err := Schedule(src, time.Hour); err != nil {
+			path := p + ".interval"
+			if src.Cron != "" {
+				path = p + ".cron"
+			}
+			add(path, "Invalid schedule: %v", err)
+		}
+	}
+
+	targets := map[string]bool{}
+	for i, target := range cnf.Targets {
+		p := fmt.Sprintf("targets[%d]", i)
+		if target.Name == "" {
+			add(p, "Target name is required")
+		} else if targets[target.Name] {
+			add(p+".name", "Duplicate target name \"%s\"", target.Name)
+		}
+		targets[target.Name] = true
+
+		if target.Trello != nil {
+			tmpl(p+".trello.token", target.Trello.Token)
+			tmpl(p+".trello.key", target.Trello.Key)
+			tmpl(p+".trello.board-id", target.Trello.BoardID)
+			tmpl(p+".trello.list-id", target.Trello.ListID)
+			if target.Trello.Mode != "" && target.Trello.Mode != trelloModeAdd && target.Trello.Mode != trelloModeUpsert {
+				add(p+".trello.mode", "Unknown mode \"%s\", supported: add, upsert", target.Trello.Mode)
+			}
+			if target.Trello.Card != nil {
+				if target.Trello.Card.Title != nil {
+					tmpl(p+".trello.card.title", *target.Trello.Card.Title)
+				}
+				if target.Trello.Card.Description != nil {
+					tmpl(p+".trello.card.description", *target.Trello.Card.Description)
+				}
+				for j, l := range target.Trello.Card.Labels {
+					tmpl(fmt.Sprintf("%s.trello.card.labels[%d]", p, j), l)
+				}
+			}
+		}
+		if target.Webhook != nil {
+			tmpl(p+".webhook.url", target.Webhook.URL)
+			for _, name := range sortedKeys(target.Webhook.Headers) {
+				tmpl(fmt.Sprintf("%s.webhook.headers.%s", p, name), target.Webhook.Headers[name])
+			}
+			tmpl(p+".webhook.body", target.Webhook.Body)
+		}
+		if target.Download != nil {
+			tmpl(p+".download.directory", target.Download.Directory)
+			tmpl(p+".download.filename", target.Download.Filename)
+			tmpl(p+".download.url", target.Download.URL)
+			if target.Download.Concurrency < 0 {
+				add(p+".download.concurrency", "Concurrency must not be negative")
+			}
+		}
+		if target.Feed != nil {
+			f := target.Feed
+			tmpl(p+".feed.path", f.Path)
+			tmpl(p+".feed.state", f.State)
+			tmpl(p+".feed.title", f.Title)
+			tmpl(p+".feed.link", f.Link)
+			tmpl(p+".feed.description", f.Description)
+			tmpl(p+".feed.item.title", f.Item.Title)
+			tmpl(p+".feed.item.link", f.Item.Link)
+			tmpl(p+".feed.item.description", f.Item.Description)
+			tmpl(p+".feed.item.published", f.Item.Published)
+			if f.Format != "" && f.Format != feedFormatRSS && f.Format != feedFormatAtom {
+				add(p+".feed.format", "Unknown format \"%s\", supported: rss, atom", f.Format)
+			}
+			if f.MaxItems < 0 {
+				add(p+".feed.max-items", "max-items must not be negative")
+			}
+		}
+		if _, err := buildTargetKind(target); err != nil {
+			add(p, "%v", err)
+		}
+	}
+
+	bindings := map[string]bool{}
+	for i, binding := range cnf.Bindings {
+		p := fmt.Sprintf("bindings[%d]", i)
+		if binding.Name == "" {
+			add(p, "Binding name is required")
+		} else if bindings[binding.Name] {
+			add(p+".name", "Duplicate binding name \"%s\"", binding.Name)
+		}
+		bindings[binding.Name] = true
+		if len(binding.Sources) == 0 && !sources[binding.Source] {
+			add(p+".source", "Source \"%s\" not found", binding.Source)
+		}
+		if len(binding.Sources) != 0 && binding.Source != "" {
+			add(p+".sources", "sources cannot be used together with source")
+		}
+		bindingSources := map[string]bool{}
+		for j, name := range binding.Sources {
+			sp := fmt.Sprintf("%s.sources[%d]", p, j)
+			if !sources[name] {
+				add(sp, "Source \"%s\" not found", name)
+			} else if bindingSources[name] {
+				add(sp, "Duplicate source \"%s\"", name)
+			}
+			bindingSources[name] = true
+		}
+		if len(binding.Targets) == 0 && !targets[binding.Target] {
+			add(p+".target", "Target \"%s\" not found", binding.Target)
+		}
+		if len(binding.Targets) != 0 && binding.Target != "" {
+			add(p+".targets", "targets cannot be used together with target")
+		}
+		bindingTargets := map[string]bool{}
+		for j, t := range binding.Targets {
+			tp := fmt.Sprintf("%s.targets[%d]", p, j)
+			if !targets[t.Name] {
+				add(tp, "Target \"%s\" not found", t.Name)
+			} else if bindingTargets[t.Name] {
+				add(tp, "Duplicate target \"%s\"", t.Name)
+			}
+			bindingTargets[t.Name] = true
+			validateFilter(tp, t.Filter)
+		}
+		if binding.Digest != nil {
+			switch binding.Digest.Window {
+			case "", digestWindowRun, digestWindowDaily, digestWindowWeekly:
+			default:
+				add(p+".digest.window", "Unknown window \"%s\", supported: run, daily, weekly", binding.Digest.Window)
+			}
+			if binding.OnRemoved != nil {
+				add(p+".digest", "digest cannot be used together with on-removed")
+			}
+			// download and feed targets deliver each item on its own
+			for _, t := range targetsOf(binding) {
+				target, err := getTarget(t.Name, cnf.Targets)
+				if err == nil && (target.Download != nil || target.Feed != nil) {
+					add(p+".digest", "Target \"%s\" does not support digest, use trello or webhook target", target.Name)
+				}
+			}
+		}
+		if binding.OnRemoved != nil {
+			actions := 0
+			if binding.OnRemoved.Archive {
+				actions++
+			}
+			if binding.OnRemoved.MoveToList != "" {
+				actions++
+				tmpl(p+".on-removed.move-to-list", binding.OnRemoved.MoveToList)
+			}
+			if binding.OnRemoved.Comment != "" {
+				actions++
+				tmpl(p+".on-removed.comment", binding.OnRemoved.Comment)
+			}
+			if actions != 1 {
+				add(p+".on-removed", "on-removed must have exactly one of: archive, move-to-list, comment")
+			}
+			for _, t := range targetsOf(binding) {
+				target, err := getTarget(t.Name, cnf.Targets)
+				if err != nil {
+					continue
+				}
+				kind, err := buildTargetKind(target)
+				if _, ok := kind.(Remover); err == nil && (!ok || (target.Trello != nil && !isTrelloUpsert(target))) {
+					add(p+".on-removed", "Target \"%s\" does not support on-removed, use trello target in upsert mode", target.Name)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// indexNodes maps the path of each value in the document to the node it was defined at
+// keys merged using "<<" point to the anchor they were defined at
+func indexNodes(n *yamlv3.Node, prefix string, index map[string]*yamlv3.Node) {
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		for _, c := range n.Content {
+			indexNodes(c, prefix, index)
+		}
+	case yamlv3.AliasNode:
+		indexNodes(n.Alias, prefix, index)
+	case yamlv3.SequenceNode:
+		for i, c := range n.Content {
+			p := fmt.Sprintf("%s[%d]", prefix, i)
+			setNode(index, p, c)
+			indexNodes(c, p, index)
+		}
+	case yamlv3.MappingNode:
+		merges := []*yamlv3.Node{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				merges = append(merges, v)
+				continue
+			}
+			p := k.Value
+			if prefix != "" {
+				p = prefix + "." + k.Value
+			}
+			setNode(index, p, k)
+			indexNodes(v, p, index)
+		}
+		// explicit keys take precedence over the merged ones
+		for _, m := range merges {
+			if m.Kind == yamlv3.SequenceNode {
+				for _, c := range m.Content {
+					indexNodes(c, prefix, index)
+				}
+				continue
+			}
+			indexNodes(m, prefix, index)
+		}
+	}
+}
+
+func setNode(index map[string]*yamlv3.Node, path string, n *yamlv3.Node) {
+	if _, ok := index[path]; !ok {
+		index[path] = n
+	}
+}
+
+// lookupNode returns the node of the path or of the closest parent that exists
+func lookupNode(index map[string]*yamlv3.Node, path string) *yamlv3.Node {
+	for path != "" {
+		if n, ok := index[path]; ok {
+			return n
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return nil
+		}
+		path = path[:i]
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
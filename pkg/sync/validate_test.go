@@ -0,0 +1,154 @@
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	const valid = `sources:
+- name: blog
+  rss:
+    url: https://blog.golang.org/feed.atom
+targets:
+- name: slack
+  webhook:
+    url: https://hooks.slack.com/x
+    body: '{{ .item.title }}'
+bindings:
+- name: blog-to-slack
+  source: blog
+  target: slack
+`
+	tests := []struct {
+		name   string
+		config string
+		// want are the errors without the file name
+		want []string
+	}{
+		{
+			name:   "valid",
+			config: valid,
+			want:   []string{},
+		},
+		{
+			name:   "unknown source and target",
+			config: strings.Replace(strings.Replace(valid, "source: blog", "source: blgo", 1), "target: slack", "targets:\n  - name: slakc", 1),
+			want: []string{
+				`12:3: Source "blgo" not found`,
+				`14:5: Target "slakc" not found`,
+			},
+		},
+		{
+			name: "bad selector",
+			config: `sources:
+- name: page
+  html:
+    url: https://example.com
+    item: li.post
+    title: h2[
+targets:
+- name: slack
+  webhook:
+    url: https://hooks.slack.com/x
+bindings:
+- name: page-to-slack
+  source: page
+  target: slack
+`,
+			want: []string{`6:5: Invalid selector: expected identifier, found EOF instead`},
+		},
+		{
+			name: "bad jq",
+			config: `sources:
+- name: api
+  json:
+    url: https://example.com/api
+    items-path: .data[
+    key-path: .id
+targets:
+- name: slack
+  webhook:
+    url: https://hooks.slack.com/x
+bindings:
+- name: api-to-slack
+  source: api
+  target: slack
+`,
+			want: []string{`5:5: Failed to parse jq expression: unexpected token <EOF>`},
+		},
+		{
+			name:   "bad template",
+			config: strings.Replace(valid, "'{{ .item.title }}'", "'{{ .item.title }'", 1),
+			want:   []string{`9:5: Failed to parse template: template: {{ .item.title }:1: unexpected "}" in operand`},
+		},
+		{
+			name: "bad filter",
+			config: strings.Replace(valid, "  target: slack\n", `  targets:
+  - name: slack
+    filter:
+      go: 'itme.title contains "Go"'
+`, 1),
+			want: []string{`16:7: Failed to compile expression: Unknown name itme, supported: item, feed, issue, event, content, file, source, binding, target`},
+		},
+		{
+			name: "position of merged key",
+			config: `common: &common
+  rss:
+    url: '{{ .x }'
+sources:
+- name: blog
+  <<: *common
+targets:
+- name: slack
+  webhook:
+    url: https://hooks.slack.com/x
+bindings:
+- name: blog-to-slack
+  source: blog
+  target: slack
+`,
+			want: []string{`3:5: Failed to parse template: template: {{ .x }:1: unexpected "}" in operand`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "validate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			name := filepath.Join(dir, "feed.yaml")
+			if err := ioutil.WriteFile(name, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, err := range ValidateFile(name) {
+				got = append(got, strings.TrimPrefix(err.Error(), name+":"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFileSyntaxError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "feed.yaml")
+	if err := ioutil.WriteFile(name, []byte("sources:\n- name: [blog\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errs := ValidateFile(name)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), name+": yaml:") {
+		t.Errorf("ValidateFile() = %v, want one yaml error", errs)
+	}
+}
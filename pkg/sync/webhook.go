@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type (
+	webhookTarget struct {
+		target Target
+	}
+)
+
+func init() {
+	RegisterTarget(targetKindWebhook, func(target Target) (TargetKind, error) {
+		if target.Webhook == nil {
+			return nil, fmt.Errorf("Target \"%s\" has no webhook config", target.Name)
+		}
+		return &webhookTarget{target: target}, nil
+	})
+}
+
+// Plan skips items that were already delivered
+func (t *webhookTarget) Plan(req DeliveryRequest) (Planned, error) {
+	if req.Previous != nil {
+		return Planned{}, nil
+	}
+	c, err := t.render(req.Data)
+	if err != nil {
+		return Planned{}, err
+	}
+	return Planned{
+		Action:      actionCreate,
+		Title:       fmt.Sprintf("%s %s", c.Method, c.URL),
+		Description: c.Body,
+		Output:      c,
+	}, nil
+}
+
+func (t *webhookTarget) Deliver(ctx context.Context, req DeliveryRequest) (map[string]string, error) {
+	c, err := t.render(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = callHTTP(ctx, req.Caller, req.FD, c)
+	return nil, err
+}
+
+// render returns the call of the item, the item is not delivered when one of the templates fails
+func (t *webhookTarget) render(data interface{}) (httpCall, error) {
+	w := t.target.Webhook
+	method := strings.ToUpper(w.Method)
+	if method == "" {
+		method = "POST"
+	}
+	headers := map[string]string{}
+	for k, v := range w.Headers {
+		h, err := renderField(fmt.Sprintf("header %s", k), v, data)
+		if err != nil {
+			return httpCall{}, err
+		}
+		headers[k] = h
+	}
+	body, err := renderField("body", w.Body, data)
+	if err != nil {
+		return httpCall{}, err
+	}
+	u, err := renderField("url", w.URL, data)
+	if err != nil {
+		return httpCall{}, err
+	}
+	return httpCall{
+		URL:     u,
+		Method:  method,
+		Headers: headers,
+		Body:    body,
+	}, nil
+}
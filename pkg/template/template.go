@@ -30,11 +30,35 @@ func String(tmpl *string, data interface{}) string {
 		return ""
 	}
 	out := new(bytes.Buffer)
+	template.Must(template.New(*tmpl).Funcs(funcs()).Parse(*tmpl)).Execute(out, data)
+	return out.String()
+}
+
+// Render executes template passing data as variables
+// unlike String, parse and execution errors are returned
+func Render(tmpl string, data interface{}) (string, error) {
+	t, err := template.New(tmpl).Funcs(funcs()).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	out := new(bytes.Buffer)
+	if err := t.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Validate returns error when the template cannot be parsed
+func Validate(tmpl string) error {
+	_, err := template.New(tmpl).Funcs(funcs()).Parse(tmpl)
+	return err
+}
+
+func funcs() template.FuncMap {
 	funcs := gomplate.Funcs(nil)
 	funcs["StartDay"] = StartDay
 	funcs["EndDay"] = EndDay
-	template.Must(template.New(*tmpl).Funcs(funcs).Parse(*tmpl)).Execute(out, data)
-	return out.String()
+	return funcs
 }
 
 // StringArray executes template on each on the items